@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"writeme/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	askFile          string
+	askIn            string
+	askBudget        int
+	askSemantic      bool
+	askMinSimilarity float64
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask <question>",
+	Short: "Ask a question about your notes",
+	Long: `Answer a question using only what NOTES.md says. The sections that look
+relevant (by matching words, and with --semantic also by embeddings) are sent
+to the AI along with the question, and the answer cites the heading path and
+line number of every note it used.
+
+If nothing in the notes looks related, or the AI can't answer from them,
+writeme says so instead of guessing.`,
+	Example: `  writeme ask "what did we decide about auth tokens?"
+  writeme ask --semantic --in Design "how are deploys rolled back?"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		question := strings.Join(args, " ")
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(askFile)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", askFile, err)
+		}
+		in := helpers.SplitSectionFlag(askIn)
+		notes := helpers.AllNotes(askFile, string(content), in)
+
+		if askSemantic && len(notes) > 0 {
+			idx, err := helpers.LoadEmbeddingIndex(askFile, helpers.EmbedModelName(cfg))
+			if err != nil {
+				return err
+			}
+			// The index is for the whole file, so rank every note (k=0
+			// keeps them all, each with its similarity filled in) and
+			// only then apply --in
+			ctx, cancel := context.WithTimeout(cmd.Context(), embedTimeout)
+			defer cancel()
+			ranked, err := helpers.SemanticSearch(ctx, cfg, idx, helpers.AllNotes(askFile, string(content), nil), question, 0)
+			if err != nil {
+				return err
+			}
+			if err := idx.Save(); err != nil {
+				return fmt.Errorf("could not save index: %w", err)
+			}
+			notes = notes[:0]
+			for _, n := range ranked {
+				if helpers.InSection(n.Section, in) {
+					notes = append(notes, n)
+				}
+			}
+		}
+
+		sections := helpers.RelevantSections(notes, question, askBudget, askMinSimilarity)
+		if len(sections) == 0 {
+			fmt.Printf("Nothing in %s looks related to that question, so there's nothing to answer from.\n", askFile)
+			return nil
+		}
+
+		answer, ok, err := helpers.AskNotes(cfg, question, sections)
+		if err != nil {
+			return fmt.Errorf("could not get an answer: %w", err)
+		}
+		if !ok {
+			fmt.Printf("%s doesn't answer that question.\n", askFile)
+			return nil
+		}
+
+		fmt.Println(answer)
+		fmt.Println()
+
+		cited := helpers.CitedNotes(answer, sections)
+		if len(cited) == 0 {
+			// No citations in the answer; show what it had to work with
+			fmt.Println(locationStyle.Render("Based on:"))
+			for _, sec := range sections {
+				fmt.Printf("  %s:%d  %s\n", askFile, sec.Notes[0].Line, sectionStyle.Render(strings.Join(sec.Path, " > ")))
+			}
+			return nil
+		}
+		fmt.Println(locationStyle.Render("Sources:"))
+		for _, hit := range cited {
+			printHit(hit)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(askCmd)
+	askCmd.Flags().StringVarP(&askFile, "file", "f", "NOTES.md", "Notes file to answer from")
+	askCmd.Flags().StringVar(&askIn, "in", "", `Only use this section and its subsections, e.g. "Design/API"`)
+	askCmd.Flags().IntVar(&askBudget, "budget", 2000, "Rough token budget for the notes sent with the question")
+	askCmd.Flags().BoolVarP(&askSemantic, "semantic", "s", false, "Also find relevant notes by meaning using embeddings")
+	askCmd.Flags().Float64Var(&askMinSimilarity, "min-similarity", 0.5, "With --semantic, how similar a note must be to count as relevant")
+}
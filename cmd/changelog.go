@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"writeme/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	changelogSince   string
+	changelogVersion string
+	changelogDate    string
+	changelogSection string
+	changelogFile    string
+	changelogOutput  string
+	changelogAI      bool
+	changelogYes     bool
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Add notes since the last release to CHANGELOG.md",
+	Long: `Collect the notes added to NOTES.md since a git tag (the latest one by
+default), sort them into Keep a Changelog categories (Added, Changed,
+Deprecated, Removed, Fixed, Security) and add them to CHANGELOG.md.
+
+Notes carrying a date are judged by that date; the rest by what git says was
+added since. Categories are guessed from the wording, or picked by the AI
+with --ai.
+
+Without --version the notes go under [Unreleased]; running it again only
+adds notes that aren't there yet. The change is previewed as a diff before
+it's written, unless --yes is given.`,
+	Example: `  writeme changelog
+  writeme changelog --since v1.2.0 --version 1.3.0 --ai
+  writeme changelog --section Release --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		content, err := os.ReadFile(changelogFile)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", changelogFile, err)
+		}
+
+		since := changelogSince
+		if since == "" {
+			// Latest tag; without one every note counts
+			if tag, err := helpers.Git("describe", "--tags", "--abbrev=0"); err == nil {
+				since = tag
+			}
+		}
+
+		var notes []helpers.SearchHit
+		for _, n := range helpers.AllNotes(changelogFile, string(content), helpers.SplitSectionFlag(changelogSection)) {
+			if !helpers.InSummary(n.Section) {
+				notes = append(notes, n)
+			}
+		}
+		if since != "" {
+			if notes, err = notesSince(changelogFile, notes, string(content), since); err != nil {
+				return err
+			}
+		}
+		if len(notes) == 0 {
+			if since != "" {
+				fmt.Printf("No new notes since %s.\n", since)
+			} else {
+				fmt.Println("No notes to add.")
+			}
+			return nil
+		}
+
+		kinds := make([]string, len(notes))
+		if changelogAI {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if kinds, err = helpers.ClassifyChanges(cfg, notes); err != nil {
+				return fmt.Errorf("could not classify notes: %w", err)
+			}
+		} else {
+			for i, n := range notes {
+				kinds[i] = helpers.GuessChangeKind(n)
+			}
+		}
+
+		entries := map[string][]string{}
+		for i, n := range notes {
+			entries[kinds[i]] = append(entries[kinds[i]], n.Text)
+		}
+
+		current, err := os.ReadFile(changelogOutput)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not read %s: %w", changelogOutput, err)
+		}
+		updated, added, err := helpers.AddChangelogEntries(string(current), changelogVersion, changelogDate, entries)
+		if err != nil {
+			return err
+		}
+		if added == 0 {
+			fmt.Printf("%s already has these notes; nothing to add.\n", changelogOutput)
+			return nil
+		}
+
+		edited := false
+		if !changelogYes {
+			draft, ok, err := helpers.RunFileDiff(changelogOutput, string(current), updated)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Changelog update cancelled.")
+				return nil
+			}
+			edited, updated = draft != updated, draft
+		}
+
+		err = helpers.UpdateFile(changelogOutput, string(current), "changelog", func(latest string, changed bool) (string, error) {
+			if !changed {
+				return updated, nil
+			}
+			if !changelogYes {
+				// The preview showed something else
+				return "", helpers.ErrChanged
+			}
+			var err error
+			updated, added, err = helpers.AddChangelogEntries(latest, changelogVersion, changelogDate, entries)
+			return updated, err
+		})
+		if err := journaled(err); err != nil {
+			return err
+		}
+		switch {
+		case edited:
+			// The count went out of date with the draft
+			fmt.Printf("Updated %s under [%s]!\n", changelogOutput, changelogVersion)
+		case added == 0:
+			fmt.Printf("%s already had these notes by then; nothing to add.\n", changelogOutput)
+		default:
+			fmt.Printf("Added %d note(s) to %s under [%s]!\n", added, changelogOutput, changelogVersion)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+	changelogCmd.Flags().StringVar(&changelogSince, "since", "", "Only notes added after this git revision or date (default: the latest tag)")
+	changelogCmd.Flags().StringVarP(&changelogVersion, "version", "v", helpers.Unreleased, "Version to add the notes under")
+	changelogCmd.Flags().StringVar(&changelogDate, "date", time.Now().Format("2006-01-02"), "Release date for --version")
+	changelogCmd.Flags().StringVarP(&changelogSection, "section", "s", "", `Only notes from this section, e.g. "Release"`)
+	changelogCmd.Flags().StringVarP(&changelogFile, "file", "f", "NOTES.md", "Notes file to collect from")
+	changelogCmd.Flags().StringVarP(&changelogOutput, "changelog", "c", "CHANGELOG.md", "Changelog file to update")
+	changelogCmd.Flags().BoolVarP(&changelogAI, "ai", "a", false, "Let the AI pick each note's category")
+	changelogCmd.Flags().BoolVarP(&changelogYes, "yes", "y", false, "Write without the preview")
+}
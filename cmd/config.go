@@ -5,6 +5,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"writeme/config"
 
 	"github.com/spf13/cobra"
 )
@@ -37,3 +39,34 @@ func init() {
 	// is called directly, e.g.:
 	// configCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+// loadConfig reads config.yaml from its usual place.
+func loadConfig() (*config.Config, error) {
+	path, err := config.ResolveConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve config path: %w", err)
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// loadConfigIfAny is loadConfig for commands that work without a config:
+// a missing file gives the zero config, a broken one a warning as well.
+func loadConfigIfAny() *config.Config {
+	path, err := config.ResolveConfigPath()
+	if err == nil {
+		if _, err = os.Stat(path); os.IsNotExist(err) {
+			return &config.Config{}
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return &config.Config{}
+	}
+	return cfg
+}
@@ -3,9 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"runtime"
 	"writeme/config"
+	"writeme/helpers"
 
 	"github.com/spf13/cobra"
 )
@@ -36,22 +35,24 @@ func runEdit() error {
 		return fmt.Errorf("config file does not exist at %s. Run 'writeme config init' first", path)
 	}
 
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		if runtime.GOOS == "windows" {
-			editor = "notepad"
-		} else {
-			editor = "vi"
-		}
+	return openInEditor(path)
+}
+
+// openInEditor opens path in $EDITOR (vi or notepad if unset) and waits for
+// it to exit. It talks to the terminal directly, so it works even when
+// stdin is a pipe.
+func openInEditor(path string) error {
+	tty, done, err := helpers.TerminalInput()
+	if err != nil {
+		return err
 	}
+	defer done()
 
-	cmd := exec.Command(editor, path)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := helpers.EditorCommand(path)
+	cmd.Stdin = tty
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to launch editor (%s): %w", editor, err)
+		return fmt.Errorf("failed to launch editor (%s): %w", cmd.Args[0], err)
 	}
 
 	return nil
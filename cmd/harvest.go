@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"writeme/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	harvestFile    string
+	harvestSection string
+	harvestPrune   bool
+	harvestDryRun  bool
+)
+
+// defaultHarvestSection is used when the config has no harvest.section.
+const defaultHarvestSection = "TODO"
+
+var harvestCmd = &cobra.Command{
+	Use:   "harvest",
+	Short: "Collect TODO, FIXME and NOTE comments from the code into NOTES.md",
+	Long: `Scan the repository for TODO, FIXME and NOTE comments (//, #, --, /* */,
+<!-- --> and friends) and add the new ones to NOTES.md, each with a link to
+the file and line it came from. Files git ignores are skipped.
+
+They go under harvest.section from config.yaml ("TODO" by default), which is
+created if needed. Comments already in the notes file are left out, wherever
+they are in it, so running harvest again only adds what's new.
+
+--prune also removes harvested notes whose comment is gone from the code.`,
+	Example: `  writeme harvest
+  writeme harvest --prune --dry-run
+  writeme harvest --section "Backlog/From code"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		content, err := os.ReadFile(harvestFile)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", harvestFile, err)
+		}
+
+		section := harvestSection
+		if section == "" {
+			section = loadConfigIfAny().Harvest.Section
+		}
+		if section == "" {
+			section = defaultHarvestSection
+		}
+
+		files, err := helpers.HarvestFiles()
+		if err != nil {
+			return fmt.Errorf("could not list files: %w", err)
+		}
+		found, err := helpers.HarvestComments(files, harvestFile)
+		if err != nil {
+			return err
+		}
+
+		contentStr := string(content)
+		fresh := helpers.NewHarvested(contentStr, found)
+		var stale []helpers.SearchHit
+		if harvestPrune {
+			stale = helpers.StaleHarvested(harvestFile, contentStr, found)
+		}
+		if len(fresh) == 0 && len(stale) == 0 {
+			fmt.Println("Nothing new to harvest.")
+			return nil
+		}
+
+		if harvestDryRun {
+			for _, h := range fresh {
+				fmt.Println("+ " + h.Note())
+			}
+			for _, n := range stale {
+				fmt.Println("- " + n.Text)
+			}
+			return nil
+		}
+
+		// Comments can't move while we write, but the notes can: if they
+		// changed, work out what's new and stale again from the new version
+		var placement []string
+		err = helpers.UpdateFile(harvestFile, contentStr, "harvest", func(current string, changed bool) (string, error) {
+			if changed {
+				fresh = helpers.NewHarvested(current, found)
+				stale = nil
+				if harvestPrune {
+					stale = helpers.StaleHarvested(harvestFile, current, found)
+				}
+			}
+			var err error
+			current, placement, err = applyHarvest(current, section, fresh, stale)
+			return current, err
+		})
+		if err := journaled(err); err != nil {
+			return err
+		}
+
+		if len(fresh) > 0 {
+			fmt.Printf("Harvested %d comment(s) into %s!\n", len(fresh), strings.Join(placement, " > "))
+		}
+		if len(stale) > 0 {
+			fmt.Printf("Pruned %d note(s) whose comment is gone.\n", len(stale))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(harvestCmd)
+	harvestCmd.Flags().StringVarP(&harvestFile, "file", "f", "NOTES.md", "Notes file to add the comments to")
+	harvestCmd.Flags().StringVarP(&harvestSection, "section", "s", "", `Section to add them under, e.g. "Backlog/TODO" (default: harvest.section from config)`)
+	harvestCmd.Flags().BoolVar(&harvestPrune, "prune", false, "Remove harvested notes whose comment no longer exists")
+	harvestCmd.Flags().BoolVarP(&harvestDryRun, "dry-run", "n", false, "Only print what would be added and removed")
+}
+
+// applyHarvest removes the stale notes from content and adds the fresh ones
+// under section, creating it if needed.
+func applyHarvest(content, section string, fresh []helpers.Harvested, stale []helpers.SearchHit) (string, []string, error) {
+	// Prune first: the line numbers in stale are for content as it is
+	if len(stale) > 0 {
+		starts := make([]int, len(stale))
+		for i, n := range stale {
+			starts[i] = n.Line
+		}
+		content = strings.Join(helpers.RemoveNotes(strings.Split(content, "\n"), starts, ""), "\n")
+	}
+	if len(fresh) == 0 {
+		return content, nil, nil
+	}
+
+	content, err := helpers.AddTopLevelHeading(content)
+	if err != nil {
+		return "", nil, err
+	}
+	content, placement, err := helpers.EnsureSection(content, helpers.SplitSectionFlag(section))
+	if err != nil {
+		return "", nil, fmt.Errorf("harvest section: %w", err)
+	}
+	notes := make([]string, len(fresh))
+	for i, h := range fresh {
+		notes[i] = h.Note()
+	}
+	content, _ = helpers.InsertNote(content, placement, notes)
+	return content, placement, nil
+}
@@ -0,0 +1,406 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"writeme/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	hooksFile          string
+	hooksPrepareCommit bool
+)
+
+// defaultHookSection is used when the config has no hooks.section.
+const defaultHookSection = "Commits"
+
+// hookAITimeout is how long the hook waits for the AI before it keeps the
+// plain subject.
+const hookAITimeout = 60 * time.Second
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Record commits in NOTES.md with git hooks",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("subcommand needed: `writeme hooks install` or `writeme hooks uninstall`")
+	},
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install a post-commit hook that adds each commit to NOTES.md",
+	Long: `Install a git post-commit hook that adds every commit's subject (and short
+hash) to NOTES.md under hooks.section from config.yaml ("Commits" by
+default), creating the section if needed. With hooks.ai: true the subject is
+reworded first; if the AI can't be reached the subject is used as is.
+
+The hook runs in the background and never fails the commit; problems are
+logged to writeme-hooks.log in the .git directory.
+
+--prepare-commit-msg also installs a hook that lists the notes added since
+the last commit as comments in the commit message, for reference.
+
+Existing hooks are kept: writeme only adds a marked block to them, which
+"writeme hooks uninstall" takes out again.`,
+	Example: `  writeme hooks install
+  writeme hooks install --prepare-commit-msg --file docs/NOTES.md`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := hooksDir()
+		if err != nil {
+			return err
+		}
+
+		// The hook runs from the top of the work tree, not from here
+		file, err := notesFromTop(hooksFile)
+		if err != nil {
+			return err
+		}
+
+		exe := "writeme"
+		if path, err := os.Executable(); err == nil {
+			exe = path
+		}
+		run := helpers.ShellQuote(exe) + " hooks run"
+		log := `"$(git rev-parse --git-dir)/writeme-hooks.log"`
+
+		hooks := map[string]string{
+			"post-commit": fmt.Sprintf("(%s post-commit --file %s </dev/null >/dev/null 2>>%s &) || true",
+				run, helpers.ShellQuote(file), log),
+		}
+		if hooksPrepareCommit {
+			hooks["prepare-commit-msg"] = fmt.Sprintf(`%s prepare-commit-msg --file %s "$1" "$2" </dev/null 2>>%s || true`,
+				run, helpers.ShellQuote(file), log)
+		}
+
+		for _, name := range []string{"post-commit", "prepare-commit-msg"} {
+			body, ok := hooks[name]
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			current, err := os.ReadFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("could not read %s: %w", path, err)
+			}
+			updated, err := helpers.AddHookBlock(string(current), body)
+			if err != nil {
+				return fmt.Errorf("could not add to %s: %w", path, err)
+			}
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("could not create %s: %w", dir, err)
+			}
+			if err := helpers.WriteFileAtomic(path, []byte(updated), 0755); err != nil {
+				return fmt.Errorf("could not write %s: %w", path, err)
+			}
+			// An existing hook keeps its permissions, but it has to run
+			if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0111 != 0111 {
+				if err := os.Chmod(path, info.Mode().Perm()|0111); err != nil {
+					return fmt.Errorf("could not make %s executable: %w", path, err)
+				}
+			}
+			fmt.Printf("Installed the %s hook in %s\n", name, path)
+		}
+		return nil
+	},
+}
+
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove writeme from the git hooks",
+	Long: `Take the writeme block out of the post-commit and prepare-commit-msg hooks.
+The rest of a hook is left alone; a hook that only ran writeme is deleted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := hooksDir()
+		if err != nil {
+			return err
+		}
+
+		removed := 0
+		for _, name := range []string{"post-commit", "prepare-commit-msg"} {
+			path := filepath.Join(dir, name)
+			current, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("could not read %s: %w", path, err)
+			}
+			updated := helpers.RemoveHookBlock(string(current))
+			if updated == string(current) {
+				continue
+			}
+
+			if helpers.HookIsEmpty(updated) {
+				err = os.Remove(path)
+			} else {
+				err = helpers.WriteFileAtomic(path, []byte(updated), 0755)
+			}
+			if err != nil {
+				return fmt.Errorf("could not update %s: %w", path, err)
+			}
+			fmt.Printf("Removed writeme from %s\n", path)
+			removed++
+		}
+		if removed == 0 {
+			fmt.Println("No writeme hooks installed.")
+		}
+		return nil
+	},
+}
+
+// hooksRunCmd is what the installed hooks call.
+var hooksRunCmd = &cobra.Command{
+	Use:    "run <hook> [hook arguments...]",
+	Short:  "Run a writeme git hook",
+	Hidden: true,
+	// Errors end up in the hook log, where usage is just noise
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "post-commit":
+			return recordCommit(hooksFile)
+		case "prepare-commit-msg":
+			if len(args) < 2 {
+				return fmt.Errorf("prepare-commit-msg needs the message file")
+			}
+			source := ""
+			if len(args) > 2 {
+				source = args[2]
+			}
+			return listNewNotes(hooksFile, args[1], source)
+		}
+		return fmt.Errorf("unknown hook %q", args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksInstallCmd, hooksUninstallCmd, hooksRunCmd)
+	hooksInstallCmd.Flags().StringVarP(&hooksFile, "file", "f", "NOTES.md", "Notes file to add commits to")
+	hooksInstallCmd.Flags().BoolVar(&hooksPrepareCommit, "prepare-commit-msg", false, "Also list new notes in the commit message template")
+	hooksRunCmd.Flags().StringVarP(&hooksFile, "file", "f", "NOTES.md", "Notes file, relative to the top of the work tree")
+}
+
+// hooksDir is where git looks for hooks, honouring core.hooksPath.
+func hooksDir() (string, error) {
+	dir, err := helpers.Git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Abs(dir)
+}
+
+// notesFromTop turns file (relative to here) into a path relative to the
+// top of the work tree.
+func notesFromTop(file string) (string, error) {
+	top, err := helpers.Git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("not in a git work tree: %w", err)
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(top, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is outside the repository", file)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// recordCommit adds HEAD's subject to the notes file. It quietly does
+// nothing for repos without the notes file, commits that only touch the
+// notes, and commits replayed by a rebase.
+func recordCommit(file string) error {
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", file, err)
+	}
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		if path, err := helpers.Git("rev-parse", "--git-path", dir); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				return nil
+			}
+		}
+	}
+
+	if helpers.GitOnlyTouches("HEAD", file) {
+		return nil
+	}
+
+	subject, err := helpers.Git("log", "-1", "--format=%s")
+	if err != nil {
+		return err
+	}
+	sha, err := helpers.Git("rev-parse", "--short", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	// The hook should work without a config; the AI just needs one
+	cfg := loadConfigIfAny()
+	section := cfg.Hooks.Section
+	if section == "" {
+		section = defaultHookSection
+	}
+
+	// Heading and section are only added in memory; they're written with
+	// the note
+	contentStr, placement, err := withHookSection(string(content), section)
+	if err != nil {
+		return err
+	}
+	if hasCommit(contentStr, placement, sha) {
+		return nil // already recorded, e.g. the hook ran twice
+	}
+
+	notes := []string{subject}
+	if cfg.Hooks.AI {
+		// Don't let a hung backend keep the hook around forever
+		ctx, cancel := context.WithTimeout(context.Background(), hookAITimeout)
+		defer cancel()
+		mode, err := helpers.ResolveMode(cfg, helpers.DefaultMode)
+		if err == nil {
+			notes, err = rewordAll(ctx, cfg, mode, contentStr, placement, notes)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "writeme: %v; keeping the commit subject\n", err)
+			notes = []string{subject}
+		}
+	}
+	notes[len(notes)-1] += " (" + sha + ")"
+
+	// The AI can take a while, and a note may have been added meanwhile:
+	// write under the lock, going from the file as it is now
+	commit := false
+	err = helpers.UpdateFile(file, string(content), "post-commit hook", func(current string, changed bool) (string, error) {
+		if changed {
+			var err error
+			if contentStr, placement, err = withHookSection(current, section); err != nil {
+				return "", err
+			}
+			if hasCommit(contentStr, placement, sha) {
+				return current, nil
+			}
+		}
+
+		// Only commit the notes if nobody else has touched them
+		if cfg.Git.AutoCommit {
+			dirty, err := helpers.GitFileDirty(file)
+			if err != nil {
+				return "", err
+			}
+			if dirty {
+				fmt.Fprintf(os.Stderr, "writeme: %s has uncommitted changes; not committing %s\n", file, sha)
+			}
+			commit = !dirty
+		}
+
+		lines := strings.Split(contentStr, "\n")
+		newLines, _ := helpers.SpliceNotes(lines, helpers.InsertionPoint(lines, placement), notes)
+		return strings.Join(newLines, "\n"), nil
+	})
+	if err := journaled(err); err != nil {
+		return err
+	}
+	if commit {
+		_, err := helpers.GitCommitNotes(file, strings.Join(placement, " > "), false)
+		return err
+	}
+	return nil
+}
+
+// withHookSection adds the top-level heading and the hooks section to
+// content if they're missing.
+func withHookSection(content, section string) (string, []string, error) {
+	content, err := helpers.AddTopLevelHeading(content)
+	if err != nil {
+		return "", nil, err
+	}
+	content, placement, err := helpers.EnsureSection(content, helpers.SplitSectionFlag(section))
+	if err != nil {
+		return "", nil, fmt.Errorf("hooks.section: %w", err)
+	}
+	return content, placement, nil
+}
+
+// hasCommit reports whether the section at placement already lists sha.
+func hasCommit(content string, placement []string, sha string) bool {
+	lines := strings.Split(content, "\n")
+	for _, h := range helpers.HeadingLines(lines) {
+		if !helpers.SamePath(helpers.HeadingPathAt(lines, h), placement) {
+			continue
+		}
+		for _, item := range helpers.SectionItems(lines, h) {
+			if strings.Contains(item.Text, "("+sha+")") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// listNewNotes adds the notes written since the last commit to the commit
+// message file as comments. It leaves messages from -m, merges and the like
+// alone.
+func listNewNotes(file, msgFile, source string) error {
+	if source != "" && source != "template" {
+		return nil
+	}
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", file, err)
+	}
+
+	rev := "HEAD"
+	if _, err := helpers.Git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		rev = "" // first commit
+	}
+	added, err := helpers.GitAddedLines(file, string(content), rev)
+	if err != nil {
+		return err
+	}
+	var notes []helpers.SearchHit
+	for _, n := range helpers.AllNotes(file, string(content), nil) {
+		if added[n.Line-1] {
+			notes = append(notes, n)
+		}
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+
+	msg, err := os.ReadFile(msgFile)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", msgFile, err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "#\n# Notes added to %s since the last commit:\n", file)
+	for _, n := range notes {
+		fmt.Fprintf(&b, "#   - %s\n", strings.ReplaceAll(n.Text, "\n", " "))
+	}
+
+	// Above git's own comments, so they're seen first
+	text := string(msg)
+	if i := strings.Index(text, "\n# "); i >= 0 && source == "" {
+		text = text[:i+1] + b.String() + text[i+1:]
+	} else {
+		text = strings.TrimRight(text, "\n") + "\n" + b.String()
+	}
+	return helpers.WriteFileAtomic(msgFile, []byte(text), 0644)
+}
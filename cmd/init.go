@@ -51,6 +51,18 @@ func runInit() error {
 	}
 	defaultConfig := `llm:
   backend: ollama
+  # Prompts are Go templates. Available fields: {{.Note}}, {{.Path}},
+  # {{.Section}}, {{.Bullets}}, {{.Project}} and {{.Date}}.
+  user_prompt: |
+    Project: {{.Project}}
+    Section: {{.Path}}
+
+    Reword this note: "{{.Note}}"
+  # Existing bullets from the target section sent as style examples. This is
+  # the only way the default prompt shows the model existing notes.
+  few_shot:
+    count: 3         # 0 turns it off
+    max_tokens: 300  # rough budget for all examples
 
 ollama:
   model: llama3.1:latest
@@ -62,6 +74,9 @@ ollama:
     - Make it clear, concise, and direct.
     - Return exactly one line.
     - Do not say "Sure", "Here", or any greeting.
+  # Used by "writeme search --semantic"; pull it with "ollama pull nomic-embed-text".
+  embed_model: nomic-embed-text
+  # embed_endpoint: http://localhost:11434/api/embed
 
 openai:
   model: gpt-4o-mini
@@ -73,6 +88,41 @@ openai:
     - Do not add or infer new information.
     - Make it direct and clear.
     - Output only the reworded line.
+  # base_url: https://api.openai.com/v1
+  embed_model: text-embedding-3-small
+
+# Custom rewrite modes for "writeme note --mode <name>". Built-ins are
+# reword, summarize, expand, grammar, translate, task and bulletize.
+# output is one of: line, bullets, task.
+modes:
+  # standup:
+  #   description: phrase the note as a standup update
+  #   system_prompt: |
+  #     Rewrite the note as a one-line standup update in past tense.
+  #   user_prompt: 'Note: "{{.Note}}"'
+  #   output: line
+
+# Used by the git hooks from "writeme hooks install".
+hooks:
+  section: Commits # heading to add commit subjects under, created if missing
+  ai: false        # reword them with the AI (the subject is kept if it fails)
+
+# Commit the notes file after each "writeme note", as "notes: add to <section>".
+git:
+  auto_commit: false
+
+# Where in the code a note was written. With capture: true (or
+# "writeme note --context") every note gets the branch and commit;
+# "--ref path:line" adds links to files. template is a Go template over
+# {{.Note}}, {{.Branch}}, {{.Commit}} and {{.Refs}} (each ref prints as a
+# Markdown link, or use {{.Path}}, {{.Start}}, {{.End}} and {{.Link}}).
+context:
+  capture: false
+  template: '{{.Note}}{{if .Commit}} _({{if .Branch}}{{.Branch}} @ {{end}}{{.Commit}})_{{end}}{{range .Refs}} {{.}}{{end}}'
+
+# Used by "writeme harvest" for TODO, FIXME and NOTE comments from the code.
+harvest:
+  section: TODO # created if missing
 `
 
 	if err := os.WriteFile(targetPath, []byte(defaultConfig), 0644); err != nil {
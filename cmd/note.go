@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"writeme/config"
@@ -10,115 +13,232 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var useAI bool
+var (
+	useAI    bool
+	examples int
+	modeName string
+	fromFile string
+	useLast  bool
+	target   string
+	amend    bool
+	withCtx  bool
+	refs     []string
+)
 
 var noteCmd = &cobra.Command{
-	Use:   "note {the note}",
+	Use:   "note [{the note} [more notes...] | -]",
 	Short: "Add a note to NOTES.md",
-	Args:  cobra.ExactArgs(1),
+	Long: `Add one or more notes to NOTES.md. Each argument becomes its own bullet;
+newlines inside an argument become indented continuation lines.
+
+--target adds to another Markdown file instead, like README.md. In sections
+written as paragraphs rather than lists, the note is added as a paragraph.
+
+Use "-" to read the note from stdin, --from-file to read it from a file, or
+give no note at all to write it in $EDITOR once you've picked a section.
+
+  git log -1 --format=%s | writeme note -
+
+--context adds the git branch and commit to each note, and --ref links a
+file or lines in it, like --ref helpers/git.go:42 (context.capture in
+config.yaml turns --context on for every note). The layout comes from
+context.template.
+
+With git.auto_commit in config.yaml the notes file is committed after each
+note, on its own; --amend-last-notes-commit folds the note into the previous
+notes commit instead, as long as that hasn't been pushed.`,
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		note := args[0]
+		notes, err := readNoteInput(args)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Flags().Changed("mode") {
+			useAI = true
+		}
 
 		var cfg *config.Config
+		var mode helpers.Mode
 		if useAI {
-			fmt.Println("AI flag is set. Validating config...")
+			cfg, err = loadConfig()
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("examples") {
+				cfg.LLM.FewShot.Count = examples
+			}
+
+			mode, err = helpers.ResolveMode(cfg, modeName)
+			if err != nil {
+				return err
+			}
+		}
 
-			path, err := config.ResolveConfigPath()
+		if cfg == nil {
+			cfg = loadConfigIfAny()
+		}
+		autoCommit := cfg.Git.AutoCommit
+		if amend && !autoCommit {
+			return fmt.Errorf("--amend-last-notes-commit needs git.auto_commit in config.yaml")
+		}
+		if autoCommit {
+			// Committing would sweep someone else's edits in with the note
+			dirty, err := helpers.GitFileDirty(target)
 			if err != nil {
-				return fmt.Errorf("could not resolve config path: %w", err)
+				fmt.Fprintf(os.Stderr, "Warning: not committing the note%s: %v\n", amendIgnored(), err)
+				autoCommit = false
+			} else if dirty {
+				return fmt.Errorf("%s has uncommitted changes; commit them first, or turn off git.auto_commit", target)
 			}
+		}
 
-			cfg, err = config.LoadConfig(path)
+		var noteCtx *helpers.NoteContext
+		if withCtx || cfg.Context.Capture || len(refs) > 0 {
+			// Capture now so a bad --ref fails before any typing is done;
+			// --ref on its own only links the files
+			ctx, err := helpers.CaptureContext(target, refs, withCtx || cfg.Context.Capture)
 			if err != nil {
-				return fmt.Errorf("could not load config: %w", err)
+				return err
 			}
+			noteCtx = &ctx
 		}
 
-		// 1. Read NOTES.md
-		content, err := os.ReadFile("NOTES.md")
+		// 1. Read NOTES.md (or --target)
+		content, err := os.ReadFile(target)
 		if err != nil {
-			return fmt.Errorf("could not read NOTES.md: %w", err)
+			return fmt.Errorf("could not read %s: %w", target, err)
 		}
 
 		// 2. Ensure top-level heading
-		contentStr, err := helpers.EnsureTopLevelHeading(string(content))
+		contentStr, err := helpers.EnsureTopLevelHeading(target, string(content))
 		if err != nil {
 			return err
 		}
 
-		// 3. Parse headings
-		tree := helpers.ParseHeadings(contentStr)
-		// fmt.Printf("Parsed headings: %+v\n", tree)
-
-		// 4. Select placement
-		placement, err := helpers.SelectPlacement(tree)
+		// 3. One screen for the rest: pick a section, write the note in
+		// $EDITOR if none was given, AI rewording, then the preview where
+		// the notes can still be edited or moved
+		// Recently used sections are nice to have; don't fail without them
+		state, err := helpers.LoadProjectState(target)
 		if err != nil {
-			return fmt.Errorf("could not select placement: %w", err)
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
-		fmt.Printf("User selected placement: %v\n", placement)
 
-		// 5. AI rewording
-		if useAI {
-			fmt.Println("AI flag is set. Processing note with AI...")
-			note, err = helpers.RewordNote(cfg, note)
-			if err != nil {
-				return fmt.Errorf("could not reword note: %w", err)
+		opts := helpers.NoteFlowOptions{
+			Name:    target,
+			Content: contentStr,
+			Notes:   notes,
+		}
+		if state != nil {
+			// Forget sections whose heading was renamed or removed
+			if state.PruneSections(helpers.AllSectionPaths(helpers.ParseHeadings(contentStr))) {
+				if err := state.Save(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: could not save state: %v\n", err)
+				}
 			}
+			opts.Recent = state.RecentPaths()
 		}
 
-		fmt.Printf("Final note to insert: %s\n", note)
-
-		// 6. Insert note — but get back both:
-		// - new content
-		// - insertAt index for preview snippet
-		newContent, insertAt := helpers.InsertNote(contentStr, placement, note)
-
-		// 7. Build preview snippet around insertAt
-		lines := strings.Split(newContent, "\n")
-
-		start := insertAt - 2
-		if start < 0 {
-			start = 0
-		}
-		end := insertAt + 2
-		if end >= len(lines) {
-			end = len(lines) - 1
+		if useLast {
+			if state == nil {
+				return fmt.Errorf("--last needs the state file, which could not be loaded")
+			}
+			last, ok := state.LastSection()
+			if !ok {
+				return fmt.Errorf("no previous section for %s yet; add a note without --last first", target)
+			}
+			opts.Placement = last
 		}
-
-		linesAbove := []string{}
-		linesBelow := []string{}
-		for i := start; i < insertAt; i++ {
-			linesAbove = append(linesAbove, lines[i])
+		if useAI {
+			opts.Reword = func(placement []string, notes []string) ([]string, error) {
+				return rewordAll(cmd.Context(), cfg, mode, contentStr, placement, notes)
+			}
 		}
-		for i := insertAt + 1; i <= end; i++ {
-			linesBelow = append(linesBelow, lines[i])
+		if noteCtx != nil {
+			// In the preview already, so the user sees what gets written
+			opts.Decorate = func(notes []string) ([]string, error) {
+				return helpers.WithContext(cfg.Context.Template, *noteCtx, notes)
+			}
 		}
 
-		// Extract the inserted line WITHOUT the "- " so user edits just the text
-		insertedLine := strings.TrimSpace(lines[insertAt])
-		insertedLine = strings.TrimPrefix(insertedLine, "- ")
-
-		finalNote, confirmed, err := helpers.RunPreviewWithEdit(linesAbove, linesBelow, insertedLine)
+		result, err := helpers.RunNoteFlow(opts)
 		if err != nil {
-			return fmt.Errorf("preview failed: %w", err)
+			return err
 		}
 
-		if !confirmed {
+		if !result.Confirmed {
 			fmt.Println("Note insertion cancelled.")
 			return nil
 		}
 
-		// 8. Rebuild the note line with "- " prefix
-		lines[insertAt] = "- " + finalNote
-		newContent = strings.Join(lines, "\n")
+		// 4. Write under the lock, checking nobody changed the file while
+		// the user was busy ($EDITOR, a git hook...). If they did, put the
+		// note in the same spot of the new version, or show the preview
+		// again
+		for {
+			var latest string
+			err := helpers.UpdateFile(target, contentStr, "note", func(current string, changed bool) (string, error) {
+				if changed {
+					if autoCommit {
+						// Whatever changed it may not be committed; don't sweep it in
+						if dirty, err := helpers.GitFileDirty(target); err != nil || dirty {
+							fmt.Fprintf(os.Stderr, "Warning: %s was changed by something else; not committing the note%s\n", target, amendIgnored())
+							autoCommit = false
+						}
+					}
+					at, ok := helpers.ReapplyInsertion(contentStr, current, result.At, result.Section)
+					if !ok {
+						latest = current
+						return "", helpers.ErrChanged
+					}
+					fmt.Printf("%s changed in the meantime; the note was added to the new version.\n", target)
+					contentStr, result.At = current, at
+				}
+				// 5. Splice the final notes in where the user left them
+				newLines, _ := helpers.SpliceNotes(strings.Split(contentStr, "\n"), result.At, result.Notes)
+				return strings.Join(newLines, "\n"), nil
+			})
+			if !errors.Is(err, helpers.ErrChanged) {
+				if err := journaled(err); err != nil {
+					return err
+				}
+				break
+			}
 
-		// 9. Write to file if confirmed
-		err = os.WriteFile("NOTES.md", []byte(newContent), 0644)
-		if err != nil {
-			return fmt.Errorf("could not write NOTES.md: %w", err)
+			contentStr = latest
+			// The notes are final by now: no more AI or context
+			opts.Content, opts.Notes, opts.Reword, opts.Decorate = contentStr, result.Notes, nil, nil
+			opts.Placement = nil
+			if helpers.InsertionPoint(strings.Split(contentStr, "\n"), result.Section) >= 0 {
+				opts.Placement = result.Section
+			}
+			fmt.Fprintf(os.Stderr, "%s changed in the meantime around where the note goes; check it against the new version.\n", target)
+			if result, err = helpers.RunNoteFlow(opts); err != nil {
+				return err
+			}
+			if !result.Confirmed {
+				fmt.Println("Note insertion cancelled.")
+				return nil
+			}
+		}
+
+		if state != nil {
+			state.TouchSection(result.Section)
+			if err := state.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not remember section: %v\n", err)
+			}
 		}
 
-		fmt.Println("Note inserted!")
+		fmt.Printf("Note inserted under %s!\n", strings.Join(result.Section, " > "))
+
+		if autoCommit {
+			subject, err := helpers.GitCommitNotes(target, strings.Join(result.Section, " > "), amend)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Committed: %s\n", subject)
+		}
 		return nil
 	},
 }
@@ -126,4 +246,66 @@ var noteCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(noteCmd)
 	noteCmd.Flags().BoolVarP(&useAI, "ai", "a", false, "Use AI to process the note")
+	noteCmd.Flags().StringVarP(&modeName, "mode", "m", helpers.DefaultMode, "AI rewrite mode: "+strings.Join(helpers.ModeNames(nil), ", ")+" or one from config (implies --ai)")
+	noteCmd.Flags().StringVarP(&fromFile, "from-file", "f", "", "Read the note from a file")
+	noteCmd.Flags().BoolVarP(&useLast, "last", "l", false, "Add to the section you used last time")
+	noteCmd.Flags().StringVarP(&target, "target", "t", "NOTES.md", "Markdown file to add the note to, e.g. README.md")
+	noteCmd.Flags().BoolVar(&amend, "amend-last-notes-commit", false, "With git.auto_commit, add to the last notes commit instead of making a new one")
+	noteCmd.Flags().BoolVarP(&withCtx, "context", "c", false, "Add the git branch and commit to the note")
+	noteCmd.Flags().StringArrayVar(&refs, "ref", nil, "Link a file from the note, as path or path:line (repeatable)")
+	noteCmd.Flags().IntVar(&examples, "examples", 0, "Number of existing bullets to send as style examples (overrides config)")
+}
+
+// amendIgnored tells the user --amend-last-notes-commit won't happen either
+// when the note isn't committed after all.
+func amendIgnored() string {
+	if amend {
+		return " (or amending the last notes commit)"
+	}
+	return ""
+}
+
+// rewordAll runs every note through the AI separately, so each can become
+// one or more bullets.
+func rewordAll(ctx context.Context, cfg *config.Config, mode helpers.Mode, content string, placement []string, notes []string) ([]string, error) {
+	var reworded []string
+	for _, note := range notes {
+		data := helpers.NewPromptData(content, placement, note)
+		out, err := helpers.RewordNote(ctx, cfg, mode, data)
+		if err != nil {
+			return nil, fmt.Errorf("could not reword note: %w", err)
+		}
+		reworded = append(reworded, out...)
+	}
+	return reworded, nil
+}
+
+// readNoteInput collects notes from the arguments, stdin ("-") or
+// --from-file. It returns no notes when the editor should be used instead.
+func readNoteInput(args []string) ([]string, error) {
+	var data []byte
+	var err error
+
+	switch {
+	case fromFile != "" && len(args) > 0:
+		return nil, fmt.Errorf("give the note as arguments or with --from-file, not both")
+	case fromFile != "":
+		data, err = os.ReadFile(fromFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", fromFile, err)
+		}
+	case len(args) == 1 && args[0] == "-":
+		data, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("could not read stdin: %w", err)
+		}
+	default:
+		return args, nil
+	}
+
+	notes := helpers.ParseNotes(string(data))
+	if len(notes) == 0 {
+		return nil, fmt.Errorf("no note found in input")
+	}
+	return notes, nil
 }
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"writeme/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteSection string
+	promoteQuery   string
+	promoteTo      string
+	promoteInto    string
+	promoteNotes   string
+	promoteAI      bool
+	promoteMark    bool
+)
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Move curated notes from NOTES.md into README.md",
+	Long: `Pick notes from NOTES.md and move them into a section of README.md.
+
+Narrow the list with --section and --search, tick the notes with space,
+then choose where they go in the README and review the change. With --ai the
+notes are rewritten as polished README text first (a paragraph in sections
+written as prose, bullets otherwise).
+
+Both files are written together once you confirm: the promoted notes are
+removed from NOTES.md, or kept and marked as promoted with --mark.`,
+	Example: `  writeme promote --section Usage
+  writeme promote --search install --ai --into Installation
+  writeme promote --to docs/guide.md --mark`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		notesContent, err := os.ReadFile(promoteNotes)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", promoteNotes, err)
+		}
+		targetContent, err := os.ReadFile(promoteTo)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", promoteTo, err)
+		}
+		// The same file under two names would take its own lock twice
+		if same, err := sameFile(promoteTo, promoteNotes); err != nil {
+			return err
+		} else if same {
+			return fmt.Errorf("--to and --file are both %s; promote into another file", promoteTo)
+		}
+
+		// 1. Pick the notes
+		candidates := helpers.PromoteCandidates(promoteNotes, string(notesContent), helpers.SplitSectionFlag(promoteSection), promoteQuery)
+		if len(candidates) == 0 {
+			fmt.Println("No notes to promote.")
+			return nil
+		}
+		picked, err := helpers.RunSelectNotes(fmt.Sprintf("Promote notes to %s", promoteTo), candidates)
+		if err != nil {
+			return err
+		}
+		if len(picked) == 0 {
+			fmt.Println("Promotion cancelled.")
+			return nil
+		}
+
+		// 2. Place them in the target, optionally polished by the AI. A
+		// target without headings gets one, or there'd be nowhere to put them
+		targetStr, err := helpers.EnsureTopLevelHeading(promoteTo, string(targetContent))
+		if err != nil {
+			return err
+		}
+		targetContent = []byte(targetStr)
+		targetLines := strings.Split(targetStr, "\n")
+
+		texts := make([]string, len(picked))
+		for i, n := range picked {
+			texts[i] = n.Text
+		}
+
+		opts := helpers.NoteFlowOptions{
+			Name:    promoteTo,
+			Content: string(targetContent),
+			Notes:   texts,
+		}
+		state, err := helpers.LoadProjectState(promoteTo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			opts.Recent = state.RecentPaths()
+		}
+		if in := helpers.SplitSectionFlag(promoteInto); len(in) > 0 {
+			h, err := helpers.FindSection(targetLines, in)
+			if err != nil {
+				return fmt.Errorf("--into: %w", err)
+			}
+			opts.Placement = helpers.HeadingPathAt(targetLines, h)
+		}
+		if promoteAI {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			opts.Reword = func(placement []string, notes []string) ([]string, error) {
+				return helpers.PolishNotes(cfg, targetLines, helpers.InsertionPoint(targetLines, placement), notes)
+			}
+		}
+
+		result, err := helpers.RunNoteFlow(opts)
+		if err != nil {
+			return err
+		}
+		if !result.Confirmed {
+			fmt.Println("Promotion cancelled.")
+			return nil
+		}
+
+		// 3. Write both files as one change. Either may have been edited
+		// while the pickers were open: the notes are found again by their
+		// text, and the target gets the same spot in its new version
+		mark := ""
+		if promoteMark {
+			mark = helpers.PromotedMark(promoteTo)
+		}
+		paths := []string{promoteTo, promoteNotes}
+		reads := []string{string(targetContent), string(notesContent)}
+		err = helpers.UpdateFiles(paths, reads, "promote", func(current []string, changed []bool) ([]string, error) {
+			lines, at := targetLines, result.At
+			if changed[0] {
+				var ok bool
+				if at, ok = helpers.ReapplyInsertion(string(targetContent), current[0], result.At, result.Section); !ok {
+					return nil, helpers.ErrChanged
+				}
+				lines = strings.Split(current[0], "\n")
+			}
+			starts, ok := helpers.LocateNotes(promoteNotes, string(notesContent), current[1], picked)
+			if !ok {
+				return nil, helpers.ErrChanged
+			}
+
+			newTarget, _ := helpers.SpliceNotes(lines, at, result.Notes)
+			newNotes := helpers.RemoveNotes(strings.Split(current[1], "\n"), starts, mark)
+			return []string{strings.Join(newTarget, "\n"), strings.Join(newNotes, "\n")}, nil
+		})
+		if err := journaled(err); err != nil {
+			return err
+		}
+
+		if state != nil {
+			state.TouchSection(result.Section)
+			if err := state.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not remember section: %v\n", err)
+			}
+		}
+
+		fmt.Printf("Promoted %d note(s) to %s > %s!\n", len(picked), promoteTo, strings.Join(result.Section, " > "))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+	promoteCmd.Flags().StringVarP(&promoteSection, "section", "s", "", `Only offer notes from this section, e.g. "Design/API"`)
+	promoteCmd.Flags().StringVarP(&promoteQuery, "search", "q", "", "Only offer notes containing this text")
+	promoteCmd.Flags().StringVarP(&promoteTo, "to", "t", "README.md", "File to promote the notes into")
+	promoteCmd.Flags().StringVar(&promoteInto, "into", "", "Section of the target to add them to (skips the picker)")
+	promoteCmd.Flags().StringVarP(&promoteNotes, "file", "f", "NOTES.md", "Notes file to promote from")
+	promoteCmd.Flags().BoolVarP(&promoteAI, "ai", "a", false, "Rewrite the notes as polished README text")
+	promoteCmd.Flags().BoolVar(&promoteMark, "mark", false, "Keep the notes in NOTES.md, marked as promoted, instead of removing them")
+}
+
+// sameFile reports whether paths a and b are the same file, however they're
+// spelled.
+func sameFile(a, b string) (bool, error) {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false, fmt.Errorf("could not read %s: %w", a, err)
+	}
+	ib, err := os.Stat(b)
+	if err != nil {
+		return false, fmt.Errorf("could not read %s: %w", b, err)
+	}
+	return os.SameFile(ia, ib), nil
+}
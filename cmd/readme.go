@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"writeme/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	readmeNotes  string
+	readmeOutput string
+	readmePrint  bool
+)
+
+var readmeCmd = &cobra.Command{
+	Use:   "readme",
+	Short: "Work with README.md",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("subcommand needed: `writeme readme generate`")
+	},
+}
+
+var readmeGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Draft README.md from your notes with AI",
+	Long: `Draft a README (overview, installation, usage, configuration) from NOTES.md,
+using the current README.md as a starting point. The change is shown as a diff
+and only written once you confirm it; press e to tweak the draft first.
+
+Sections you want to keep exactly as they are can be marked as hand-written
+by putting this line right under their heading:
+
+  ` + helpers.KeepMarker,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		notes, err := os.ReadFile(readmeNotes)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", readmeNotes, err)
+		}
+
+		current, err := os.ReadFile(readmeOutput)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not read %s: %w", readmeOutput, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Drafting %s from %s...\n", readmeOutput, readmeNotes)
+		draft, err := helpers.GenerateReadme(cfg, string(notes), string(current))
+		if err != nil {
+			return fmt.Errorf("could not generate README: %w", err)
+		}
+
+		if readmePrint {
+			fmt.Print(draft)
+			return nil
+		}
+
+		final, ok, err := helpers.RunFileDiff(readmeOutput, string(current), draft)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("README generation cancelled.")
+			return nil
+		}
+
+		if err := journaled(helpers.UpdateFile(readmeOutput, string(current), "readme generate", helpers.Unchanged(final))); err != nil {
+			return err
+		}
+		fmt.Printf("%s updated!\n", readmeOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(readmeCmd)
+	readmeCmd.AddCommand(readmeGenerateCmd)
+	readmeGenerateCmd.Flags().StringVarP(&readmeNotes, "notes", "n", "NOTES.md", "Notes file to draft from")
+	readmeGenerateCmd.Flags().StringVarP(&readmeOutput, "file", "f", "README.md", "README file to update")
+	readmeGenerateCmd.Flags().BoolVar(&readmePrint, "print", false, "Print the draft instead of previewing and writing it")
+}
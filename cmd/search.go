@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"writeme/helpers"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchRegex bool
+	searchFuzzy bool
+	searchJSON  bool
+	searchIn    string
+	searchSince string
+	searchUntil string
+	searchFiles []string
+
+	searchSemantic bool
+	searchTop      int
+	searchReindex  bool
+)
+
+var (
+	locationStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	sectionStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5"))
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search your notes",
+	Long: `Search headings and bullets in NOTES.md (or the files given with --file).
+Each hit is printed with its heading path and line number.
+
+Dates are read from YYYY-MM-DD timestamps in the notes; with --since or
+--until, notes without a timestamp are left out.
+
+With --semantic, bullets are ranked by meaning instead of wording, using the
+embedding model from config.yaml. Vectors are cached in the state dir and only
+new or changed bullets are embedded again.`,
+	Example: `  writeme search token
+  writeme search --regex 'auth(n|z)'
+  writeme search --fuzzy rtlmt --in "Design/API"
+  writeme search deploy --since 2025-01-01 --json
+  writeme search --semantic "how do we rotate tokens" -k 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := helpers.SearchOptions{
+			Mode: helpers.SearchSubstring,
+			In:   helpers.SplitSectionFlag(searchIn),
+		}
+		switch {
+		case searchSemantic && (searchRegex || searchFuzzy):
+			return fmt.Errorf("--semantic can't be combined with --regex or --fuzzy")
+		case searchRegex && searchFuzzy:
+			return fmt.Errorf("--regex and --fuzzy can't be used together")
+		case searchRegex:
+			opts.Mode = helpers.SearchRegex
+		case searchFuzzy:
+			opts.Mode = helpers.SearchFuzzy
+		}
+
+		var err error
+		if opts.Since, err = parseDateFlag("since", searchSince); err != nil {
+			return err
+		}
+		if opts.Until, err = parseDateFlag("until", searchUntil); err != nil {
+			return err
+		}
+
+		var hits []helpers.SearchHit
+		if searchSemantic {
+			hits, err = semanticSearch(cmd.Context(), args[0], opts)
+		} else {
+			hits, err = keywordSearch(args[0], opts)
+		}
+		if err != nil {
+			return err
+		}
+
+		if searchJSON {
+			if hits == nil {
+				hits = []helpers.SearchHit{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(hits)
+		}
+
+		if len(hits) == 0 {
+			fmt.Println("No matches.")
+			return nil
+		}
+		for _, hit := range hits {
+			printHit(hit)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().BoolVarP(&searchRegex, "regex", "r", false, "Treat the query as a regular expression")
+	searchCmd.Flags().BoolVarP(&searchFuzzy, "fuzzy", "z", false, "Fuzzy match the query, best matches first")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Print hits as JSON")
+	searchCmd.Flags().StringVar(&searchIn, "in", "", `Only search this section and its subsections, e.g. "Design/API"`)
+	searchCmd.Flags().StringVar(&searchSince, "since", "", "Only notes dated on or after YYYY-MM-DD")
+	searchCmd.Flags().StringVar(&searchUntil, "until", "", "Only notes dated on or before YYYY-MM-DD")
+	searchCmd.Flags().BoolVarP(&searchSemantic, "semantic", "s", false, "Rank notes by meaning using embeddings")
+	searchCmd.Flags().IntVarP(&searchTop, "top", "k", 5, "Number of hits to show with --semantic")
+	searchCmd.Flags().BoolVar(&searchReindex, "reindex", false, "Throw away cached embeddings and embed everything again")
+	searchCmd.Flags().StringSliceVarP(&searchFiles, "file", "f", []string{"NOTES.md"}, "Notes file(s) to search")
+}
+
+// keywordSearch runs the substring/regex/fuzzy search over every file.
+func keywordSearch(query string, opts helpers.SearchOptions) ([]helpers.SearchHit, error) {
+	var hits []helpers.SearchHit
+	for _, file := range searchFiles {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file, err)
+		}
+		fileHits, err := helpers.SearchNotes(file, string(content), query, opts)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, fileHits...)
+	}
+	return hits, nil
+}
+
+// embedTimeout bounds the embedding calls of a semantic search, indexing
+// the notes included.
+const embedTimeout = 5 * time.Minute
+
+// semanticSearch embeds the bullets of every file (reusing cached vectors)
+// and returns the top --top hits across all of them.
+func semanticSearch(ctx context.Context, query string, opts helpers.SearchOptions) ([]helpers.SearchHit, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, embedTimeout)
+	defer cancel()
+	model := helpers.EmbedModelName(cfg)
+
+	var hits []helpers.SearchHit
+	for _, file := range searchFiles {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", file, err)
+		}
+
+		idx, err := helpers.LoadEmbeddingIndex(file, model)
+		if err != nil {
+			return nil, err
+		}
+		if searchReindex {
+			idx.Reset()
+		}
+		// The index is for the whole file, so rank every note and only
+		// then apply --in and --since/--until
+		ranked, err := helpers.SemanticSearch(ctx, cfg, idx, helpers.AllNotes(file, string(content), nil), query, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := idx.Save(); err != nil {
+			return nil, fmt.Errorf("could not save index: %w", err)
+		}
+		for _, n := range ranked {
+			if helpers.InSection(n.Section, opts.In) && inDateRange(n, opts) {
+				hits = append(hits, n)
+			}
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Similarity > hits[j].Similarity })
+	if len(hits) > searchTop {
+		hits = hits[:searchTop]
+	}
+	return hits, nil
+}
+
+// inDateRange applies --since/--until the same way keyword search does.
+func inDateRange(hit helpers.SearchHit, opts helpers.SearchOptions) bool {
+	if opts.Since.IsZero() && opts.Until.IsZero() {
+		return true
+	}
+	t, ok := helpers.NoteDate(hit.Text)
+	if !ok {
+		return false
+	}
+	return !t.Before(opts.Since) && (opts.Until.IsZero() || !t.After(opts.Until))
+}
+
+func printHit(hit helpers.SearchHit) {
+	loc := locationStyle.Render(fmt.Sprintf("%s:%d", hit.File, hit.Line))
+	if hit.Similarity > 0 {
+		loc += locationStyle.Render(fmt.Sprintf("  (%.2f)", hit.Similarity))
+	}
+	fmt.Printf("%s  %s\n", loc, sectionStyle.Render(strings.Join(hit.Section, " > ")))
+	if hit.Kind == "heading" {
+		fmt.Println("    (heading)")
+		return
+	}
+	for _, line := range helpers.FormatNotes([]string{hit.Text}) {
+		fmt.Println("    " + line)
+	}
+}
+
+// parseDateFlag parses a YYYY-MM-DD flag value; empty means no limit.
+func parseDateFlag(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--%s wants a date like 2025-01-31: %w", name, err)
+	}
+	return t, nil
+}
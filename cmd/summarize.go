@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"writeme/helpers"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+var (
+	summarizeSection     string
+	summarizeSince       string
+	summarizeWrite       bool
+	summarizeFile        string
+	summarizeChunkTokens int
+)
+
+var summarizeCmd = &cobra.Command{
+	Use:   "summarize",
+	Short: "Summarize a section of your notes with AI",
+	Long: `Summarize the notes in a section (and its subsections) into a few bullets.
+Without --section the whole file is summarized.
+
+Big sections are summarized in chunks of about --chunk-tokens tokens and the
+partial summaries merged afterwards.
+
+--since takes a date (YYYY-MM-DD) or a git revision and only summarizes
+notes added after it. Notes carrying a date are judged by that date; the
+rest by what git says was added since.
+
+With --write, the summary is saved in a "Summary" subsection of the section,
+replacing the previous one; otherwise it is only printed.`,
+	Example: `  writeme summarize --section "Design/API"
+  writeme summarize --since v1.2.0
+  writeme summarize --section Decisions --since 2025-06-01 --write`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(summarizeFile)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", summarizeFile, err)
+		}
+		lines := strings.Split(string(content), "\n")
+
+		h := -1
+		if in := helpers.SplitSectionFlag(summarizeSection); len(in) > 0 {
+			if h, err = helpers.FindSection(lines, in); err != nil {
+				return err
+			}
+		} else if headings := helpers.HeadingLines(lines); len(headings) > 0 {
+			h = headings[0]
+		} else {
+			return fmt.Errorf("%s has no headings to summarize", summarizeFile)
+		}
+		section := helpers.HeadingPathAt(lines, h)
+
+		notes := helpers.NotesUnder(summarizeFile, lines, h)
+		if summarizeSince != "" {
+			notes, err = notesSince(summarizeFile, notes, string(content), summarizeSince)
+			if err != nil {
+				return err
+			}
+		}
+		if len(notes) == 0 {
+			fmt.Printf("No notes to summarize under %s.\n", strings.Join(section, " > "))
+			return nil
+		}
+
+		texts := make([]string, len(notes))
+		for i, n := range notes {
+			texts[i] = n.Text
+		}
+		summary, err := helpers.SummarizeNotes(cfg, section, texts, summarizeChunkTokens)
+		if err != nil {
+			return fmt.Errorf("could not summarize: %w", err)
+		}
+
+		fmt.Println(sectionStyle.Render(strings.Join(section, " > ")) + locationStyle.Render(fmt.Sprintf("  (%d notes)", len(notes))))
+		for _, line := range helpers.FormatNotes(summary) {
+			fmt.Println(line)
+		}
+
+		if !summarizeWrite {
+			return nil
+		}
+		err = helpers.UpdateFile(summarizeFile, string(content), "summarize", func(current string, changed bool) (string, error) {
+			lines, h := lines, h
+			if changed {
+				// Notes were added meanwhile; find the section again
+				var err error
+				lines = strings.Split(current, "\n")
+				if h, err = helpers.FindSection(lines, section); err != nil {
+					return "", err
+				}
+			}
+			return strings.Join(helpers.WriteSummary(lines, h, summary, time.Now()), "\n"), nil
+		})
+		if err := journaled(err); err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Println(lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("Saved under %s > %s.", strings.Join(section, " > "), helpers.SummaryHeading)))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(summarizeCmd)
+	summarizeCmd.Flags().StringVarP(&summarizeSection, "section", "s", "", `Section to summarize, e.g. "Design/API" (default: the whole file)`)
+	summarizeCmd.Flags().StringVar(&summarizeSince, "since", "", "Only notes added after this date (YYYY-MM-DD) or git revision")
+	summarizeCmd.Flags().BoolVarP(&summarizeWrite, "write", "w", false, `Save the summary in a "Summary" subsection`)
+	summarizeCmd.Flags().StringVarP(&summarizeFile, "file", "f", "NOTES.md", "Notes file to summarize")
+	summarizeCmd.Flags().IntVar(&summarizeChunkTokens, "chunk-tokens", 1500, "Rough token size of each chunk for big sections")
+}
+
+// notesSince keeps the notes of file added after since, a date or a git
+// revision.
+func notesSince(file string, notes []helpers.SearchHit, content, since string) ([]helpers.SearchHit, error) {
+	date, dateErr := time.Parse("2006-01-02", since)
+
+	rev, useGit := since, true
+	if dateErr == nil {
+		var err error
+		if rev, err = helpers.GitRevBefore(date); err != nil {
+			// Not a git repo: only dated notes can be judged
+			fmt.Fprintf(os.Stderr, "Warning: %v; notes without a date are left out\n", err)
+			useGit = false
+		}
+	}
+
+	var added map[int]bool
+	if useGit {
+		var err error
+		added, err = helpers.GitAddedLines(file, content, rev)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var kept []helpers.SearchHit
+	for _, n := range notes {
+		if t, ok := helpers.NoteDate(n.Text); ok && dateErr == nil {
+			if !t.Before(date) {
+				kept = append(kept, n)
+			}
+			continue
+		}
+		if added != nil && added[n.Line-1] {
+			kept = append(kept, n)
+		}
+	}
+	return kept, nil
+}
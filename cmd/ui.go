@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"writeme/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Browse and edit NOTES.md in a full-screen UI",
+	Long: `Opens NOTES.md in a full-screen browser: sections on the left, bullets on the
+right. Add, edit, delete, reorder and move bullets between sections; every
+change is saved straight away.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := os.Stat("NOTES.md"); err != nil {
+			return fmt.Errorf("could not open NOTES.md (run 'writeme create' first): %w", err)
+		}
+		return helpers.RunBrowser("NOTES.md")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"writeme/helpers"
+
+	"github.com/spf13/cobra"
+)
+
+var undoFile string
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo writeme's last change to NOTES.md",
+	Long: fmt.Sprintf(`Put NOTES.md back the way it was before writeme's last change to it, like a
+note, a harvest or a save in writeme ui. writeme remembers its last %d
+changes per file, so undo can be repeated, and writeme redo takes it back.
+
+If the file was edited by something else since, undo refuses rather than
+lose those edits.`, helpers.JournalSize),
+	Example: `  writeme undo
+  writeme undo --file README.md`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stepJournal(undoFile, false)
+	},
+}
+
+var redoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Redo the change writeme undo took back",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stepJournal(undoFile, true)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd, redoCmd)
+	for _, c := range []*cobra.Command{undoCmd, redoCmd} {
+		c.Flags().StringVarP(&undoFile, "file", "f", "NOTES.md", "File to undo or redo changes in")
+	}
+}
+
+// stepJournal undoes (or redoes) one change to file. It holds the file's
+// lock throughout, like every other writer.
+func stepJournal(file string, redo bool) error {
+	unlock, err := helpers.LockFile(file)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read %s: %w", file, err)
+	}
+	j, err := helpers.LoadJournal(file)
+	if err != nil {
+		return err
+	}
+
+	step, verb := j.Undo, "Undid"
+	if redo {
+		step, verb = j.Redo, "Redid"
+	}
+	entry, data, err := step(current)
+	if err != nil {
+		return err
+	}
+
+	if entry.Created && !redo {
+		// The edit made the file; put back that it wasn't there
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("could not remove %s: %w", file, err)
+		}
+	} else if err := helpers.WriteFileAtomic(file, data, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", file, err)
+	}
+	if err := j.Save(); err != nil {
+		return fmt.Errorf("%s was restored, but the journal could not be saved: %w", file, err)
+	}
+	fmt.Printf("%s %s from %s in %s.\n", verb, entry.Action, entry.Time.Format("2006-01-02 15:04"), file)
+	return nil
+}
+
+// journaled turns a failure to record an edit for undo into a warning: the
+// edit itself was written.
+func journaled(err error) error {
+	if errors.Is(err, helpers.ErrJournal) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	return err
+}
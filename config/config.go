@@ -11,26 +11,75 @@ import (
 
 // Top-level config struct matching your YAML layout
 type Config struct {
-	LLM    LLMConfig    `yaml:"llm"`
-	Ollama OllamaConfig `yaml:"ollama"`
-	OpenAI OpenAIConfig `yaml:"openai"`
+	LLM     LLMConfig             `yaml:"llm"`
+	Ollama  OllamaConfig          `yaml:"ollama"`
+	OpenAI  OpenAIConfig          `yaml:"openai"`
+	Modes   map[string]ModeConfig `yaml:"modes"`
+	Hooks   HooksConfig           `yaml:"hooks"`
+	Git     GitConfig             `yaml:"git"`
+	Context ContextConfig         `yaml:"context"`
+	Harvest HarvestConfig         `yaml:"harvest"`
 }
 
 // Exported sub-structs for reusability across packages
 type LLMConfig struct {
-	Backend string `yaml:"backend"`
+	Backend    string        `yaml:"backend"`
+	UserPrompt string        `yaml:"user_prompt"` // text/template, shared by every backend
+	FewShot    FewShotConfig `yaml:"few_shot"`
 }
 
-type OllamaConfig struct {
-	Model        string `yaml:"model"`
-	Endpoint     string `yaml:"endpoint"`
+// FewShotConfig controls how many existing bullets from the target section
+// are sent along as style examples.
+type FewShotConfig struct {
+	Count     int `yaml:"count"`      // 0 turns it off
+	MaxTokens int `yaml:"max_tokens"` // rough budget for all examples, 0 = no limit
+}
+
+// ModeConfig is a user-defined rewrite mode for `writeme note --mode`.
+type ModeConfig struct {
+	Description  string `yaml:"description"`
 	SystemPrompt string `yaml:"system_prompt"`
+	UserPrompt   string `yaml:"user_prompt"`
+	Output       string `yaml:"output"` // line, bullets or task
+}
+
+// HooksConfig is used by the git hooks from `writeme hooks install`.
+type HooksConfig struct {
+	Section string `yaml:"section"` // heading commits are added under, e.g. "Log/Commits"
+	AI      bool   `yaml:"ai"`      // reword commit subjects, falls back to the subject
+}
+
+// GitConfig controls committing notes as they're added.
+type GitConfig struct {
+	AutoCommit bool `yaml:"auto_commit"` // commit the notes file after each note
+}
+
+// ContextConfig controls the branch, commit and file references added to
+// notes, see `writeme note --context`.
+type ContextConfig struct {
+	Capture  bool   `yaml:"capture"`  // add them to every note
+	Template string `yaml:"template"` // text/template for the whole bullet
+}
+
+// HarvestConfig is used by `writeme harvest`.
+type HarvestConfig struct {
+	Section string `yaml:"section"` // heading TODO comments go under, e.g. "Backlog/TODO"
+}
+
+type OllamaConfig struct {
+	Model         string `yaml:"model"`
+	Endpoint      string `yaml:"endpoint"`
+	SystemPrompt  string `yaml:"system_prompt"`
+	EmbedModel    string `yaml:"embed_model"`    // for semantic search
+	EmbedEndpoint string `yaml:"embed_endpoint"` // defaults to /api/embed next to endpoint
 }
 
 type OpenAIConfig struct {
 	Model        string `yaml:"model"`
 	APIKey       string `yaml:"api_key"`
 	SystemPrompt string `yaml:"system_prompt"`
+	BaseURL      string `yaml:"base_url"`    // defaults to https://api.openai.com/v1
+	EmbedModel   string `yaml:"embed_model"` // for semantic search
 }
 
 // Global vars used by main.go and elsewhere
@@ -79,6 +128,41 @@ func ResolveConfigPath() (string, error) {
 	return filepath.Clean(finalPath), nil
 }
 
+// ResolveStateDir returns the directory writeme keeps state in between runs,
+// like recently used sections. It follows the same rules as
+// ResolveConfigPath, using XDG_STATE_HOME (~/.local/state) or %LOCALAPPDATA%.
+func ResolveStateDir() (string, error) {
+	// Allow user override via WRITEME_STATE_DIR env var
+	if stateEnv := os.Getenv("WRITEME_STATE_DIR"); stateEnv != "" {
+		return filepath.Clean(stateEnv), nil
+	}
+
+	var stateDir string
+	if runtime.GOOS != "windows" {
+		if xdg := os.Getenv("XDG_STATE_HOME"); xdg != "" {
+			stateDir = xdg
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("could not get user home dir: %w", err)
+			}
+			stateDir = filepath.Join(home, ".local", "state")
+		}
+	} else {
+		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+			stateDir = localAppData
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("could not get user home dir: %w", err)
+			}
+			stateDir = filepath.Join(home, "AppData", "Local")
+		}
+	}
+
+	return filepath.Clean(filepath.Join(stateDir, "writeme")), nil
+}
+
 // LoadConfig reads and parses the config file from the given path
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -0,0 +1,204 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"writeme/config"
+)
+
+// NotFoundReply is what the model is told to answer when the notes don't
+// cover the question.
+const NotFoundReply = "NOT FOUND"
+
+const askSystemPrompt = `You answer questions about a software project using only the project's notes.
+Each note starts with its citation in square brackets, like [Design > API:12].
+Rules:
+- Use only what the notes say. Do not guess or add outside knowledge.
+- After each sentence, cite the notes it is based on using their brackets exactly.
+- Keep the answer short and direct.
+- If the notes don't answer the question, reply with exactly: ` + NotFoundReply
+
+// AskSection is a section of the notes file picked as context for a question.
+type AskSection struct {
+	Path  []string
+	Notes []SearchHit // in file order
+	Score float64     // best note score, for ordering
+}
+
+// stopWords are left out when matching question words against notes.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true, "why": true,
+	"how": true, "did": true, "does": true, "do": true, "about": true, "with": true,
+	"that": true, "this": true, "these": true, "those": true, "have": true, "has": true,
+	"our": true, "we": true, "you": true, "is": true, "of": true, "to": true, "in": true,
+	"on": true, "it": true, "a": true, "an": true, "any": true, "from": true, "there": true,
+	"decide": true, "decided": true,
+}
+
+var wordPattern = regexp.MustCompile(`[\pL\pN_]+`)
+
+// questionTerms returns the words of a question worth looking for.
+func questionTerms(question string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, w := range wordPattern.FindAllString(strings.ToLower(question), -1) {
+		if len(w) < 3 || stopWords[w] {
+			continue
+		}
+		// "tokens" should still find "token"
+		if len(w) > 4 {
+			w = strings.TrimSuffix(w, "s")
+		}
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// keywordScore is the share of terms found in the note, counting a match in
+// its heading path as half.
+func keywordScore(terms []string, note SearchHit) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	text := strings.ToLower(note.Text)
+	path := strings.ToLower(strings.Join(note.Section, " "))
+
+	var score float64
+	for _, t := range terms {
+		switch {
+		case strings.Contains(text, t):
+			score++
+		case strings.Contains(path, t):
+			score += 0.5
+		}
+	}
+	return score / float64(len(terms))
+}
+
+// RelevantSections picks the sections worth sending for question. A note is
+// relevant if it shares words with the question or, when embeddings were
+// used, its Similarity is at least minSimilarity. Whole sections are sent
+// while they fit in budget tokens, otherwise just their relevant notes.
+func RelevantSections(notes []SearchHit, question string, budget int, minSimilarity float64) []AskSection {
+	terms := questionTerms(question)
+
+	bySection := map[string]*AskSection{}
+	relevant := map[string][]SearchHit{}
+	var order []string
+	for _, n := range notes {
+		key := strings.Join(n.Section, PathSeparator)
+		sec, ok := bySection[key]
+		if !ok {
+			sec = &AskSection{Path: n.Section}
+			bySection[key] = sec
+			order = append(order, key)
+		}
+		sec.Notes = append(sec.Notes, n)
+
+		score := keywordScore(terms, n)
+		if n.Similarity >= minSimilarity && n.Similarity > 0 {
+			score += n.Similarity
+		} else if score == 0 {
+			continue
+		}
+		relevant[key] = append(relevant[key], n)
+		sec.Score = max(sec.Score, score)
+	}
+
+	var picked []AskSection
+	for _, key := range order {
+		if len(relevant[key]) > 0 {
+			picked = append(picked, *bySection[key])
+		}
+	}
+	sort.SliceStable(picked, func(i, j int) bool { return picked[i].Score > picked[j].Score })
+
+	var fitted []AskSection
+	used := 0
+	for _, sec := range picked {
+		cost := EstimateTokens(renderSection(sec))
+		if budget > 0 && used+cost > budget {
+			sec.Notes = relevant[strings.Join(sec.Path, PathSeparator)]
+			cost = EstimateTokens(renderSection(sec))
+			if used+cost > budget {
+				continue
+			}
+		}
+		used += cost
+		fitted = append(fitted, sec)
+	}
+	return fitted
+}
+
+// Citation is how a note is referred to in the prompt and the answer.
+func Citation(note SearchHit) string {
+	return fmt.Sprintf("[%s:%d]", strings.Join(note.Section, PathSeparator), note.Line)
+}
+
+func renderSection(sec AskSection) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n", strings.Join(sec.Path, PathSeparator))
+	for _, n := range sec.Notes {
+		text := strings.ReplaceAll(n.Text, "\n", "\n  ")
+		fmt.Fprintf(&b, "%s %s\n", Citation(n), text)
+	}
+	return b.String()
+}
+
+// AskNotes asks the configured backend to answer question from sections. ok
+// is false when the model says the notes don't cover it.
+func AskNotes(cfg *config.Config, question string, sections []AskSection) (answer string, ok bool, err error) {
+	var b strings.Builder
+	b.WriteString("Notes:\n\n")
+	for _, sec := range sections {
+		b.WriteString(renderSection(sec))
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Question: %s", question)
+
+	messages := []ChatMessage{
+		{Role: "system", Content: askSystemPrompt},
+		{Role: "user", Content: b.String()},
+	}
+	reply, err := Chat(cfg, messages)
+	if err != nil {
+		return "", false, err
+	}
+
+	reply = strings.TrimSpace(reply)
+	// Only the sentinel on its own: real answers can say "not found" too
+	if reply == "" || strings.TrimSuffix(reply, ".") == NotFoundReply {
+		return "", false, nil
+	}
+	return reply, true, nil
+}
+
+// CitedNotes returns the notes from sections that answer cites, in the
+// order they are first cited.
+func CitedNotes(answer string, sections []AskSection) []SearchHit {
+	type cited struct {
+		at   int
+		note SearchHit
+	}
+	var found []cited
+	for _, sec := range sections {
+		for _, n := range sec.Notes {
+			if at := strings.Index(answer, Citation(n)); at >= 0 {
+				found = append(found, cited{at, n})
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].at < found[j].at })
+
+	notes := make([]SearchHit, len(found))
+	for i, c := range found {
+		notes[i] = c.note
+	}
+	return notes
+}
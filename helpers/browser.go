@@ -0,0 +1,563 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	activePane    = paneStyle.BorderForeground(lipgloss.Color("5"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	movingStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+)
+
+const sidebarWidth = 32
+
+// sidebarRow is a heading shown in the sidebar.
+type sidebarRow struct {
+	line        int // index of the heading line
+	level       int
+	title       string
+	key         string // heading path, used to remember collapsed sections
+	hasChildren bool
+}
+
+// BrowserModel is the full-screen notes browser behind `writeme ui`: the
+// heading tree on the left, the selected section's bullets on the right.
+// Every change is written back to the file straight away.
+type BrowserModel struct {
+	path      string
+	lines     []string
+	rows      []sidebarRow
+	collapsed map[string]bool
+	cursor    int // selected sidebar row
+	item      int // selected bullet in the section
+	inContent bool
+
+	editor  textarea.Model
+	editing bool
+	editIdx int // bullet being edited, -1 for a new one
+
+	moving        *SectionItem // bullet picked up with "m"
+	movingKey     string
+	confirmDelete bool
+
+	status string
+	width  int
+	height int
+}
+
+// NewBrowserModel loads the notes file at path.
+func NewBrowserModel(path string) (BrowserModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return BrowserModel{}, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	m := BrowserModel{
+		path:      path,
+		lines:     strings.Split(string(data), "\n"),
+		collapsed: map[string]bool{},
+	}
+	m.buildRows()
+	return m, nil
+}
+
+// buildRows lists the headings that aren't hidden under a collapsed parent.
+func (m *BrowserModel) buildRows() {
+	heads := HeadingLines(m.lines)
+	m.rows = nil
+
+	hiddenBelow := 0 // headings deeper than this are hidden; 0 = none
+	for i, h := range heads {
+		level, title, _ := parseHeading(m.lines[h])
+		if hiddenBelow > 0 && level > hiddenBelow {
+			continue
+		}
+		hiddenBelow = 0
+
+		key := strings.Join(HeadingPathAt(m.lines, h), "\x00")
+		hasChildren := false
+		if i+1 < len(heads) {
+			next, _, _ := parseHeading(m.lines[heads[i+1]])
+			hasChildren = next > level
+		}
+
+		m.rows = append(m.rows, sidebarRow{line: h, level: level, title: title, key: key, hasChildren: hasChildren})
+		if hasChildren && m.collapsed[key] {
+			hiddenBelow = level
+		}
+	}
+
+	if m.cursor >= len(m.rows) {
+		m.cursor = len(m.rows) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	m.clampItem()
+}
+
+func (m *BrowserModel) clampItem() {
+	n := len(m.items())
+	if m.item >= n {
+		m.item = n - 1
+	}
+	if m.item < 0 {
+		m.item = 0
+	}
+}
+
+// items returns the bullets of the selected section.
+func (m BrowserModel) items() []SectionItem {
+	if len(m.rows) == 0 {
+		return nil
+	}
+	return SectionItems(m.lines, m.rows[m.cursor].line)
+}
+
+// selectKey moves the sidebar cursor to the heading with the given key.
+func (m *BrowserModel) selectKey(key string) {
+	for i, row := range m.rows {
+		if row.key == key {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// apply replaces the document, writes it to disk and refreshes the view.
+// If the file was changed outside the browser in the meantime, nothing is
+// written and that version is loaded instead.
+func (m *BrowserModel) apply(lines []string, status string) {
+	key := ""
+	if len(m.rows) > 0 {
+		key = m.rows[m.cursor].key
+	}
+
+	var latest string
+	err := UpdateFile(m.path, strings.Join(m.lines, "\n"), "ui", func(current string, changed bool) (string, error) {
+		latest = current
+		if changed {
+			return "", ErrChanged
+		}
+		return strings.Join(lines, "\n"), nil
+	})
+	switch {
+	case errors.Is(err, ErrChanged):
+		m.lines = strings.Split(latest, "\n")
+		m.status = filepath.Base(m.path) + " was changed outside writeme, so it was reloaded instead; try again."
+	// Undo history is nice to have; the save itself is what matters here
+	case err != nil && !errors.Is(err, ErrJournal):
+		m.status = "Could not save: " + err.Error()
+	default:
+		m.lines = lines
+		m.status = status
+	}
+
+	m.buildRows()
+	m.selectKey(key)
+	m.clampItem()
+}
+
+func (m BrowserModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m BrowserModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		if m.editing {
+			m.editor.SetWidth(m.contentWidth() - 2)
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.editing {
+			return m.updateEditor(msg)
+		}
+
+		key := msg.String()
+		if m.confirmDelete {
+			m.confirmDelete = false
+			if key == "y" {
+				m.deleteItem()
+			} else {
+				m.status = "Delete cancelled."
+			}
+			return m, nil
+		}
+
+		m.status = ""
+		switch key {
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		case "tab":
+			m.inContent = !m.inContent
+			return m, nil
+		case "esc":
+			if m.moving != nil {
+				m.moving = nil
+				m.status = "Move cancelled."
+			}
+			return m, nil
+		}
+
+		if m.inContent {
+			return m.updateContent(key)
+		}
+		return m.updateSidebar(key)
+	}
+
+	return m, nil
+}
+
+func (m BrowserModel) updateSidebar(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+			m.item = 0
+		}
+	case "down", "j":
+		if m.cursor < len(m.rows)-1 {
+			m.cursor++
+			m.item = 0
+		}
+	case "left", "h":
+		if len(m.rows) > 0 && m.rows[m.cursor].hasChildren {
+			m.collapsed[m.rows[m.cursor].key] = true
+			m.buildRows()
+		}
+	case "right", "l":
+		if len(m.rows) > 0 {
+			m.collapsed[m.rows[m.cursor].key] = false
+			m.buildRows()
+		}
+	case " ":
+		if len(m.rows) > 0 && m.rows[m.cursor].hasChildren {
+			k := m.rows[m.cursor].key
+			m.collapsed[k] = !m.collapsed[k]
+			m.buildRows()
+		}
+	case "enter":
+		if m.moving != nil {
+			m.dropItem()
+			return m, nil
+		}
+		m.inContent = true
+	case "a":
+		return m.startEdit(-1)
+	}
+	return m, nil
+}
+
+func (m BrowserModel) updateContent(key string) (tea.Model, tea.Cmd) {
+	items := m.items()
+
+	switch key {
+	case "up", "k":
+		if m.item > 0 {
+			m.item--
+		}
+	case "down", "j":
+		if m.item < len(items)-1 {
+			m.item++
+		}
+	case "left", "h":
+		m.inContent = false
+	case "a":
+		return m.startEdit(-1)
+	case "e", "enter":
+		if len(items) > 0 {
+			return m.startEdit(m.item)
+		}
+	case "d":
+		if len(items) > 0 {
+			m.confirmDelete = true
+			m.status = "Delete this bullet? (y/N)"
+		}
+	case "K", "shift+up":
+		if m.item > 0 {
+			m.swapItems(m.item-1, m.item)
+			m.item--
+		}
+	case "J", "shift+down":
+		if m.item < len(items)-1 {
+			m.swapItems(m.item, m.item+1)
+			m.item++
+		}
+	case "m":
+		if len(items) > 0 {
+			it := items[m.item]
+			m.moving = &it
+			m.movingKey = m.rows[m.cursor].key
+			m.inContent = false
+			m.status = "Pick a section and press Enter to move the bullet there (Esc to cancel)."
+		}
+	}
+	return m, nil
+}
+
+// startEdit opens the editor on bullet idx, or on a new bullet if idx < 0.
+func (m BrowserModel) startEdit(idx int) (tea.Model, tea.Cmd) {
+	if len(m.rows) == 0 {
+		m.status = "No sections to add to."
+		return m, nil
+	}
+
+	var notes []string
+	if idx >= 0 {
+		notes = []string{m.items()[idx].Text}
+	}
+	m.editor = NewNoteEditor(notes)
+	m.editor.SetWidth(m.contentWidth() - 2)
+	m.editing = true
+	m.editIdx = idx
+	return m, textarea.Blink
+}
+
+func (m BrowserModel) updateEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.editing = false
+		m.status = "Edit cancelled."
+		return m, nil
+	case "ctrl+s":
+		m.editing = false
+		notes := ParseNotes(m.editor.Value())
+		m.saveEdit(notes)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	m.editor.SetHeight(editorHeight(m.editor.Value()))
+	return m, cmd
+}
+
+// saveEdit writes the edited (or new) bullets back into the section.
+func (m *BrowserModel) saveEdit(notes []string) {
+	if m.editIdx < 0 {
+		if len(notes) == 0 {
+			m.status = "Nothing to add."
+			return
+		}
+		at := sectionInsertionPoint(m.lines, m.rows[m.cursor].line)
+		lines, _ := SpliceNotes(m.lines, at, notes)
+		m.apply(lines, "Bullet added.")
+		m.item = len(m.items()) - 1
+		return
+	}
+
+	it := m.items()[m.editIdx]
+	lines := append([]string{}, m.lines[:it.Start]...)
+	lines = append(lines, formatItems(notes, listMarkerAt(m.lines, it.Start))...)
+	lines = append(lines, m.lines[it.End:]...)
+	if len(notes) == 0 {
+		m.apply(lines, "Bullet removed.")
+		return
+	}
+	m.apply(lines, "Bullet saved.")
+}
+
+func (m *BrowserModel) deleteItem() {
+	it := m.items()[m.item]
+	lines := append([]string{}, m.lines[:it.Start]...)
+	lines = append(lines, m.lines[it.End:]...)
+	m.apply(lines, "Bullet deleted.")
+}
+
+// swapItems swaps bullets i and j (i < j) of the selected section, leaving
+// anything between them where it is.
+func (m *BrowserModel) swapItems(i, j int) {
+	items := m.items()
+	a, b := items[i], items[j]
+
+	lines := append([]string{}, m.lines[:a.Start]...)
+	lines = append(lines, m.lines[b.Start:b.End]...)
+	lines = append(lines, m.lines[a.End:b.Start]...)
+	lines = append(lines, m.lines[a.Start:a.End]...)
+	lines = append(lines, m.lines[b.End:]...)
+	m.apply(lines, "Bullet moved.")
+}
+
+// dropItem moves the picked-up bullet to the end of the selected section.
+func (m *BrowserModel) dropItem() {
+	it := *m.moving
+	m.moving = nil
+	target := m.rows[m.cursor].key
+	if target == m.movingKey {
+		m.status = "That's where it already is."
+		return
+	}
+
+	// Other edits may have shifted it since it was picked up; look for it
+	// again by its text
+	found := false
+	if h, err := FindSection(m.lines, strings.Split(m.movingKey, "\x00")); err == nil {
+		for _, cur := range SectionItems(m.lines, h) {
+			if cur.Text == it.Text {
+				it, found = cur, true
+				break
+			}
+		}
+	}
+	if !found {
+		m.status = "The bullet changed since it was picked up; pick it up again."
+		return
+	}
+
+	lines := append([]string{}, m.lines[:it.Start]...)
+	lines = append(lines, m.lines[it.End:]...)
+
+	placement := strings.Split(target, "\x00")
+	at := InsertionPoint(lines, placement)
+	if at < 0 {
+		m.status = "Section not found."
+		return
+	}
+	lines, _ = SpliceNotes(lines, at, []string{it.Text})
+	m.apply(lines, "Bullet moved to "+strings.Join(placement, " > ")+".")
+	m.inContent = true
+	m.item = len(m.items()) - 1
+}
+
+func (m BrowserModel) contentWidth() int {
+	w := m.width - sidebarWidth - 4
+	if w < 20 {
+		w = 20
+	}
+	return w
+}
+
+func (m BrowserModel) View() string {
+	if m.width == 0 {
+		return "\n  Loading..."
+	}
+	paneHeight := m.height - 4 // borders and help line
+
+	sidebar := paneStyle
+	content := activePane
+	if !m.inContent && !m.editing {
+		sidebar, content = activePane, paneStyle
+	}
+
+	left := sidebar.Width(sidebarWidth).Height(paneHeight).Render(m.sidebarView(paneHeight))
+	right := content.Width(m.contentWidth()).Height(paneHeight).Render(m.contentView(paneHeight))
+
+	help := "Tab switch pane · ↑/↓ select · ←/→ collapse/expand · a add · q quit"
+	switch {
+	case m.editing:
+		help = "Ctrl+S save · Esc cancel · start a line with \"- \" for another bullet"
+	case m.moving != nil:
+		help = "↑/↓ pick section · Enter move here · Esc cancel"
+	case m.inContent:
+		help = "e edit · a add · d delete · J/K reorder · m move to section · ← sections · q quit"
+	}
+	footer := helpStyle.Render(help)
+	if m.status != "" {
+		footer = m.status + "  " + footer
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, right) + "\n" + footer
+}
+
+func (m BrowserModel) sidebarView(height int) string {
+	var rows []string
+	for i, row := range m.rows {
+		marker := "  "
+		if row.hasChildren {
+			marker = "▾ "
+			if m.collapsed[row.key] {
+				marker = "▸ "
+			}
+		}
+		text := strings.Repeat("  ", row.level-1) + marker + row.title
+		text = ansi.Truncate(text, sidebarWidth, "…")
+		if i == m.cursor {
+			if m.moving != nil {
+				text = movingStyle.Render(text)
+			}
+			text = selectedStyle.Render(text)
+		}
+		rows = append(rows, text)
+	}
+	return strings.Join(scrollWindow(rows, m.cursor, height), "\n")
+}
+
+func (m BrowserModel) contentView(height int) string {
+	if len(m.rows) == 0 {
+		return "No headings yet. Run `writeme note` to add some."
+	}
+
+	row := m.rows[m.cursor]
+	title := headingStyle.Render(strings.Join(HeadingPathAt(m.lines, row.line), " > "))
+
+	var editorView string
+	if m.editing {
+		editorView = "\n" + m.editor.View()
+		height -= m.editor.Height() + 1
+	}
+
+	items := m.items()
+	if len(items) == 0 {
+		return title + "\n\n(no bullets in this section)" + editorView
+	}
+
+	var rows []string
+	selectedRow := 0
+	for i, it := range items {
+		block := HighlightMarkdown(m.lines[it.Start:it.End])
+		if i == m.item && m.inContent {
+			selectedRow = len(rows)
+			block[0] = selectedStyle.Render(m.lines[it.Start])
+		}
+		rows = append(rows, block...)
+	}
+
+	return title + "\n\n" + strings.Join(scrollWindow(rows, selectedRow, height-2), "\n") + editorView
+}
+
+// scrollWindow returns at most height rows around selected.
+func scrollWindow(rows []string, selected, height int) []string {
+	if height <= 0 || len(rows) <= height {
+		return rows
+	}
+	start := selected - height/2
+	if start < 0 {
+		start = 0
+	}
+	if start+height > len(rows) {
+		start = len(rows) - height
+	}
+	return rows[start : start+height]
+}
+
+// RunBrowser opens the notes browser on path.
+func RunBrowser(path string) error {
+	m, err := NewBrowserModel(path)
+	if err != nil {
+		return err
+	}
+
+	tty, done, err := TerminalInput()
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	p := tea.NewProgram(m, tea.WithInput(tty), tea.WithAltScreen())
+	_, err = p.Run()
+	return err
+}
@@ -0,0 +1,231 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"writeme/config"
+)
+
+// ChangeKinds are the Keep a Changelog categories, in the order they appear
+// in a version section.
+var ChangeKinds = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+// Unreleased is the version name for changes that aren't released yet.
+const Unreleased = "Unreleased"
+
+const changelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+
+const classifySystemPrompt = `You sort a project's notes into changelog categories.
+The categories are: Added, Changed, Deprecated, Removed, Fixed, Security.
+Reply with one line per note in the form "<number>: <category>" and nothing else.`
+
+// kindHints guess the category from words in the note or its section.
+var kindHints = []struct {
+	kind  string
+	words *regexp.Regexp
+}{
+	{"Security", regexp.MustCompile(`(?i)\b(security|cve|vulnerab\w*|xss|csrf|injection)\b`)},
+	{"Fixed", regexp.MustCompile(`(?i)\b(fix\w*|bugs?|crash\w*|broken|regression|issue)\b`)},
+	{"Deprecated", regexp.MustCompile(`(?i)\bdeprecat\w*\b`)},
+	{"Removed", regexp.MustCompile(`(?i)\b(remov\w*|drop\w*|delet\w*)\b`)},
+	{"Added", regexp.MustCompile(`(?i)\b(add\w*|new|introduc\w*|support\w*|features?)\b`)},
+}
+
+// GuessChangeKind picks a category for a note without asking the AI. The
+// note's own words win over its section's title.
+func GuessChangeKind(note SearchHit) string {
+	for _, text := range []string{note.Text, strings.Join(note.Section, " ")} {
+		for _, hint := range kindHints {
+			if hint.words.MatchString(text) {
+				return hint.kind
+			}
+		}
+	}
+	return "Changed"
+}
+
+var classifyLine = regexp.MustCompile(`^\s*(\d+)\s*[:.)-]\s*\**([A-Za-z]+)`)
+
+// ClassifyChanges asks the configured backend for each note's category.
+// Notes the reply doesn't cover fall back to GuessChangeKind.
+func ClassifyChanges(cfg *config.Config, notes []SearchHit) ([]string, error) {
+	var b strings.Builder
+	for i, n := range notes {
+		fmt.Fprintf(&b, "%d: %s\n", i+1, strings.ReplaceAll(n.Text, "\n", " "))
+	}
+
+	messages := []ChatMessage{
+		{Role: "system", Content: classifySystemPrompt},
+		{Role: "user", Content: b.String()},
+	}
+	reply, err := Chat(cfg, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := make([]string, len(notes))
+	for _, line := range strings.Split(reply, "\n") {
+		m := classifyLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		i, _ := strconv.Atoi(m[1])
+		if i < 1 || i > len(notes) {
+			continue
+		}
+		for _, kind := range ChangeKinds {
+			if strings.EqualFold(m[2], kind) {
+				kinds[i-1] = kind
+			}
+		}
+	}
+	for i := range kinds {
+		if kinds[i] == "" {
+			kinds[i] = GuessChangeKind(notes[i])
+		}
+	}
+	return kinds, nil
+}
+
+// versionTitle matches "[1.2.0] - 2025-01-31" style headings.
+var versionTitle = regexp.MustCompile(`^\[?([^\]\s]+)\]?`)
+
+func changelogVersion(title string) string {
+	if m := versionTitle.FindStringSubmatch(title); m != nil {
+		return m[1]
+	}
+	return title
+}
+
+// AddChangelogEntries adds entries (category -> bullets) to the section for
+// version in a Keep a Changelog file, creating the file's header and the
+// section as needed. A new release goes below Unreleased, above older
+// releases; entries already in the section are skipped. It returns how many
+// entries were added, and fails if version was already released, so a
+// release is never edited by accident.
+func AddChangelogEntries(content, version, date string, entries map[string][]string) (string, int, error) {
+	if strings.TrimSpace(content) == "" {
+		content = changelogHeader
+	}
+	lines := strings.Split(content, "\n")
+
+	// Find the version's section, or where a new one goes
+	sec, at := -1, len(lines)
+	for _, h := range HeadingLines(lines) {
+		level, title, _ := parseHeading(lines[h])
+		if level != 2 {
+			continue
+		}
+		v := changelogVersion(title)
+		if strings.EqualFold(v, version) {
+			sec = h
+			break
+		}
+		if !strings.EqualFold(v, Unreleased) && at == len(lines) {
+			at = h
+		}
+	}
+
+	if sec < 0 {
+		heading := fmt.Sprintf("## [%s]", version)
+		if version != Unreleased {
+			heading += " - " + date
+		}
+		block := []string{heading, ""}
+		if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+			block = append([]string{""}, block...)
+		}
+		lines = splice(lines, at, at, block)
+		sec = at + len(block) - 2
+	} else if version != Unreleased {
+		return "", 0, fmt.Errorf("the changelog already has a section for %s", version)
+	}
+
+	// A note that's already listed under any category is skipped, so
+	// re-running with --ai (or after recategorizing by hand) doesn't list
+	// it twice
+	existing := map[string]bool{}
+	for _, h := range HeadingLines(lines) {
+		if h < sec {
+			continue
+		}
+		if level, _, _ := parseHeading(lines[h]); h > sec && level <= 2 {
+			break
+		}
+		for _, item := range SectionItems(lines, h) {
+			existing[item.Text] = true
+		}
+	}
+	added := 0
+	for _, kind := range ChangeKinds {
+		var fresh []string
+		for _, b := range entries[kind] {
+			if !existing[b] {
+				existing[b] = true
+				fresh = append(fresh, b)
+			}
+		}
+		if len(fresh) > 0 {
+			lines = addToKind(lines, sec, kind, fresh)
+			added += len(fresh)
+		}
+	}
+	return strings.Join(lines, "\n"), added, nil
+}
+
+// addToKind appends bullets under "### kind" in the version section on line
+// sec, adding the subsection in category order if it's missing.
+func addToKind(lines []string, sec int, kind string, bullets []string) []string {
+	end := len(lines)
+	sub, before := -1, -1
+	for _, h := range HeadingLines(lines) {
+		if h <= sec {
+			continue
+		}
+		level, title, _ := parseHeading(lines[h])
+		if level <= 2 {
+			end = h
+			break
+		}
+		if strings.EqualFold(title, kind) {
+			sub = h
+		} else if before < 0 && kindIndex(title) > kindIndex(kind) {
+			before = h
+		}
+	}
+
+	if sub >= 0 {
+		out, _ := SpliceNotes(lines, sectionInsertionPoint(lines, sub), bullets)
+		return out
+	}
+
+	at := end
+	if before >= 0 {
+		at = before
+	}
+	// Back up over blank lines so the subsection sits right after the content
+	for at > sec+1 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	block := append([]string{"", "### " + kind}, FormatNotes(bullets)...)
+	if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		block = append(block, "")
+	}
+	return splice(lines, at, at, block)
+}
+
+func kindIndex(title string) int {
+	for i, k := range ChangeKinds {
+		if strings.EqualFold(k, title) {
+			return i
+		}
+	}
+	return len(ChangeKinds)
+}
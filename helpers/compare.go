@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	removedWordStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Strikethrough(true)
+	addedWordStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	columnTitleStyle = lipgloss.NewStyle().Bold(true).Underline(true)
+)
+
+// RenderComparison shows the original notes and the AI version side by side.
+// Words the AI dropped are struck through on the left; words it added are
+// highlighted on the right.
+func RenderComparison(original, ai []string, width int) string {
+	ops := Diff(strings.Fields(strings.Join(original, "\n")), strings.Fields(strings.Join(ai, "\n")))
+
+	var left, right []string
+	for _, op := range ops {
+		switch op.Kind {
+		case DiffEqual:
+			left = append(left, op.Text)
+			right = append(right, op.Text)
+		case DiffDelete:
+			left = append(left, removedWordStyle.Render(op.Text))
+		case DiffInsert:
+			right = append(right, addedWordStyle.Render(op.Text))
+		}
+	}
+
+	colWidth := (width - 3) / 2
+	if colWidth < 20 {
+		colWidth = 20
+	}
+	leftCol := lipgloss.NewStyle().Width(colWidth).PaddingRight(1)
+	rightCol := lipgloss.NewStyle().Width(colWidth).PaddingLeft(1).
+		Border(lipgloss.NormalBorder(), false, false, false, true)
+
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		leftCol.Render(columnTitleStyle.Render("Original")+"\n"+strings.Join(left, " ")),
+		rightCol.Render(columnTitleStyle.Render("AI")+"\n"+strings.Join(right, " ")),
+	)
+}
@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// DefaultNoteTemplate is used when the config doesn't set context.template.
+const DefaultNoteTemplate = `{{.Note}}{{if .Commit}} _({{if .Branch}}{{.Branch}} @ {{end}}{{.Commit}})_{{end}}{{range .Refs}} {{.}}{{end}}`
+
+// NoteContext is what a note template can reference: the note plus where
+// in the code it was written.
+type NoteContext struct {
+	Note   string
+	Branch string    // current branch, empty outside git or on a detached HEAD
+	Commit string    // short HEAD hash, empty outside git
+	Refs   []NoteRef // from --ref
+}
+
+// NoteRef points at a file, or lines in it, from a note.
+type NoteRef struct {
+	Path       string // as given, relative to the current directory
+	Start, End int    // 1-based lines, 0 when not given
+	Link       string // Path relative to the notes file's directory
+}
+
+// String renders the ref as a Markdown link, with a #L12 or #L12-L20
+// anchor for lines like GitHub and GitLab use.
+func (r NoteRef) String() string {
+	text, anchor := r.Path, ""
+	if r.Start > 0 {
+		text += fmt.Sprintf(":%d", r.Start)
+		anchor = fmt.Sprintf("#L%d", r.Start)
+		if r.End > r.Start {
+			text += fmt.Sprintf("-%d", r.End)
+			anchor += fmt.Sprintf("-L%d", r.End)
+		}
+	}
+	return fmt.Sprintf("[%s](%s%s)", text, r.Link, anchor)
+}
+
+var refPattern = regexp.MustCompile(`^(.+?)(?::(\d+)(?:-(\d+))?)?$`)
+
+// ParseRef reads a "path", "path:12" or "path:12-20" reference, linking it
+// relative to notesFile.
+func ParseRef(ref, notesFile string) (NoteRef, error) {
+	m := refPattern.FindStringSubmatch(ref)
+	if m == nil {
+		return NoteRef{}, fmt.Errorf("bad reference %q, want path or path:line", ref)
+	}
+	r := NoteRef{Path: filepath.ToSlash(filepath.Clean(m[1]))}
+	fmt.Sscan(m[2], &r.Start)
+	fmt.Sscan(m[3], &r.End)
+	if r.End > 0 && r.End < r.Start {
+		return NoteRef{}, fmt.Errorf("bad reference %q, the range ends before it starts", ref)
+	}
+	if _, err := os.Stat(m[1]); err != nil {
+		return NoteRef{}, fmt.Errorf("bad reference %q: %w", ref, err)
+	}
+
+	abs, err := filepath.Abs(m[1])
+	if err != nil {
+		return NoteRef{}, err
+	}
+	notesDir, err := filepath.Abs(filepath.Dir(notesFile))
+	if err != nil {
+		return NoteRef{}, err
+	}
+	link, err := filepath.Rel(notesDir, abs)
+	if err != nil {
+		link = abs
+	}
+	r.Link = filepath.ToSlash(link)
+	// Markdown links break on spaces
+	r.Link = strings.ReplaceAll(r.Link, " ", "%20")
+	return r, nil
+}
+
+// CaptureContext parses refs for notes going into notesFile and, with
+// withGit, collects the git branch and commit (when in a repo).
+func CaptureContext(notesFile string, refs []string, withGit bool) (NoteContext, error) {
+	var ctx NoteContext
+	if withGit {
+		if branch, err := Git("rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+			ctx.Branch = branch
+		}
+		if commit, err := Git("rev-parse", "--short", "HEAD"); err == nil {
+			ctx.Commit = commit
+		}
+	}
+	for _, ref := range refs {
+		r, err := ParseRef(ref, notesFile)
+		if err != nil {
+			return NoteContext{}, err
+		}
+		ctx.Refs = append(ctx.Refs, r)
+	}
+	return ctx, nil
+}
+
+// WithContext renders each note through the note template tmpl (the
+// default one if empty). The template's output is trimmed, and a note's
+// continuation lines come through as they are.
+func WithContext(tmpl string, ctx NoteContext, notes []string) ([]string, error) {
+	if tmpl == "" {
+		tmpl = DefaultNoteTemplate
+	}
+	t, err := template.New("note").Funcs(promptFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse note template: %w", err)
+	}
+
+	out := make([]string, len(notes))
+	for i, note := range notes {
+		ctx.Note = note
+		var b bytes.Buffer
+		if err := t.Execute(&b, ctx); err != nil {
+			return nil, fmt.Errorf("could not render note template: %w", err)
+		}
+		out[i] = strings.TrimSpace(b.String())
+	}
+	return out, nil
+}
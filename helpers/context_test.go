@@ -0,0 +1,27 @@
+package helpers
+
+import "testing"
+
+func TestWithContextDefaultTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  NoteContext
+		want string
+	}{
+		{"branch", NoteContext{Branch: "main", Commit: "abc1234"}, "fix the cache _(main @ abc1234)_"},
+		{"detached HEAD", NoteContext{Commit: "abc1234"}, "fix the cache _(abc1234)_"},
+		{"outside git", NoteContext{}, "fix the cache"},
+		{"refs only", NoteContext{Refs: []NoteRef{{Path: "a.go", Start: 3, Link: "a.go"}}}, "fix the cache [a.go:3](a.go#L3)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WithContext("", tt.ctx, []string{"fix the cache"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package helpers
+
+// DiffKind says whether a token is in both sequences or only one.
+type DiffKind int
+
+const (
+	DiffEqual  DiffKind = iota
+	DiffDelete          // only in the old sequence
+	DiffInsert          // only in the new sequence
+)
+
+// DiffOp is one token of a diff.
+type DiffOp struct {
+	Kind DiffKind
+	Text string
+}
+
+// Diff returns the shortest edit script turning a into b, using the longest
+// common subsequence. Fine for notes and READMEs; it's O(len(a)*len(b)).
+func Diff(a, b []string) []DiffOp {
+	// lcs[i][j] = length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []DiffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffOp{DiffEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, DiffOp{DiffDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, DiffOp{DiffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, DiffOp{DiffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, DiffOp{DiffInsert, b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,96 @@
+package helpers
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// diffView is the scrolling diff both previews are built around: the
+// title, a header line and the diff in a viewport, with the changes to jump
+// between. The models around it add their own editing.
+type diffView struct {
+	viewport viewport.Model
+	changes  []int // rendered line of each change, for jumping
+	ready    bool
+}
+
+// diffAction is what a key does to a preview as a whole.
+type diffAction int
+
+const (
+	diffNone diffAction = iota
+	diffConfirm
+	diffCancel
+)
+
+// diffKey maps the keys every preview shares to confirming or cancelling.
+func diffKey(key string) diffAction {
+	switch key {
+	case "enter", "y", "ctrl+s":
+		return diffConfirm
+	case "q", "esc", "ctrl+c":
+		return diffCancel
+	}
+	return diffNone
+}
+
+// resize gives the diff the full width and height lines.
+func (d *diffView) resize(width, height int) {
+	if !d.ready {
+		d.viewport = viewport.New(width, 1)
+		d.ready = true
+	}
+	d.viewport.Width = width
+	d.viewport.Height = max(3, height)
+}
+
+// setContent shows lines, with changes the lines jump stops at.
+func (d *diffView) setContent(lines []string, changes []int) {
+	if len(lines) == 0 {
+		lines = []string{gutterStyle.Render("(no changes)")}
+	}
+	d.changes = changes
+	d.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// scrollTo puts line at the top, or in the upper third with third set.
+func (d *diffView) scrollTo(line int, third bool) {
+	if third {
+		line -= d.viewport.Height / 3
+	}
+	d.viewport.SetYOffset(line)
+}
+
+// jump scrolls to the next (dir 1) or previous (dir -1) change.
+func (d *diffView) jump(dir int) {
+	top := d.viewport.YOffset
+	if dir > 0 {
+		for _, c := range d.changes {
+			if c > top {
+				d.viewport.SetYOffset(c)
+				return
+			}
+		}
+		return
+	}
+	for i := len(d.changes) - 1; i >= 0; i-- {
+		if d.changes[i] < top {
+			d.viewport.SetYOffset(d.changes[i])
+			return
+		}
+	}
+}
+
+// update scrolls the diff.
+func (d diffView) update(msg tea.Msg) (diffView, tea.Cmd) {
+	var cmd tea.Cmd
+	d.viewport, cmd = d.viewport.Update(msg)
+	return d, cmd
+}
+
+// view renders the title, header and diff, ending with a newline.
+func (d diffView) view(header string) string {
+	return titleStyle.Render("--- Proposed Change Preview ---") + "\n" + header + "\n" + d.viewport.View() + "\n"
+}
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// EditorCommand returns a command opening path in $EDITOR (vi or notepad if
+// unset). Stdin is left for the caller to wire up.
+func EditorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		} else {
+			editor = "vi"
+		}
+	}
+
+	cmd := exec.Command(editor, path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// NoteTemplate is what the editor opens with when a note is written in
+// $EDITOR: an empty first line and a commented view of the target section.
+func NoteTemplate(content string, placement []string) string {
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString("# Write your note above. Lines starting with '#' are ignored.\n")
+	b.WriteString("# Start a line with \"- \" to add another bullet.\n")
+	b.WriteString("#\n")
+	fmt.Fprintf(&b, "# Adding to: %s\n", strings.Join(placement, " > "))
+	if bullets := SectionBullets(content, placement); len(bullets) > 0 {
+		b.WriteString("#\n# Already in this section:\n")
+		for _, line := range FormatNotes(bullets) {
+			b.WriteString("#   " + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// ParseNoteTemplate reads notes back from an edited NoteTemplate. Lines
+// starting with '#' are dropped, like git's commit message template.
+func ParseNoteTemplate(text string) []string {
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			kept = append(kept, line)
+		}
+	}
+	return ParseNotes(strings.Join(kept, "\n"))
+}
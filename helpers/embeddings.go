@@ -0,0 +1,149 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"writeme/config"
+)
+
+const (
+	defaultOllamaEmbedModel = "nomic-embed-text"
+	defaultOpenAIEmbedModel = "text-embedding-3-small"
+)
+
+// EmbedModelName identifies the embedding model in use, so cached vectors
+// from a different model are never mixed in.
+func EmbedModelName(cfg *config.Config) string {
+	switch cfg.LLM.Backend {
+	case "openai":
+		return "openai:" + orDefault(cfg.OpenAI.EmbedModel, defaultOpenAIEmbedModel)
+	default:
+		return "ollama:" + orDefault(cfg.Ollama.EmbedModel, defaultOllamaEmbedModel)
+	}
+}
+
+// Embed returns one vector per text from whichever backend the config
+// selects.
+func Embed(ctx context.Context, cfg *config.Config, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	switch cfg.LLM.Backend {
+	case "ollama":
+		return EmbedWithOllama(ctx, &cfg.Ollama, texts)
+	case "openai":
+		return EmbedWithOpenAI(ctx, &cfg.OpenAI, texts)
+	default:
+		return nil, fmt.Errorf("unsupported backend: %s", cfg.LLM.Backend)
+	}
+}
+
+func EmbedWithOllama(ctx context.Context, cfg *config.OllamaConfig, texts []string) ([][]float64, error) {
+	endpoint := cfg.EmbedEndpoint
+	if endpoint == "" {
+		// http://localhost:11434/api/chat -> http://localhost:11434/api/embed
+		endpoint = strings.TrimSuffix(cfg.Endpoint, "/chat") + "/embed"
+	}
+
+	payload := map[string]interface{}{
+		"model": orDefault(cfg.EmbedModel, defaultOllamaEmbedModel),
+		"input": texts,
+	}
+	bodyBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("bad status: %s, body: %s", resp.Status, string(b))
+	}
+
+	var result struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("could not decode response JSON: %w", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("asked for %d embeddings, got %d", len(texts), len(result.Embeddings))
+	}
+	return result.Embeddings, nil
+}
+
+func EmbedWithOpenAI(ctx context.Context, cfg *config.OpenAIConfig, texts []string) ([][]float64, error) {
+	payload := map[string]interface{}{
+		"model": orDefault(cfg.EmbedModel, defaultOpenAIEmbedModel),
+		"input": texts,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", openAIURL(cfg, "embeddings"), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("bad status: %s, body: %s", resp.Status, respBody)
+	}
+
+	var res struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("decode error: %w", err)
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, d := range res.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+	"writeme/config"
+)
+
+func TestEmbedWithOllama(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("request to %s, want /api/embed", r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+		if req.Model != defaultOllamaEmbedModel {
+			t.Errorf("model %q, want %q", req.Model, defaultOllamaEmbedModel)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": [][]float64{{1, 0}, {0, 1}}})
+	}))
+	defer srv.Close()
+
+	// The embed endpoint is worked out from the chat one
+	cfg := &config.OllamaConfig{Endpoint: srv.URL + "/api/chat"}
+	got, err := EmbedWithOllama(context.Background(), cfg, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{1, 0}, {0, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEmbedWithOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("request to %s, want /v1/embeddings", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization %q", got)
+		}
+		// Out of order on purpose: the index says which input it is
+		json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{
+			{"index": 1, "embedding": []float64{0, 1}},
+			{"index": 0, "embedding": []float64{1, 0}},
+		}})
+	}))
+	defer srv.Close()
+
+	cfg := &config.OpenAIConfig{APIKey: "sk-test", BaseURL: srv.URL + "/v1/"}
+	got, err := EmbedWithOpenAI(context.Background(), cfg, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float64{{1, 0}, {0, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestEmbedWithOpenAIMissingVector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"data": []map[string]any{
+			{"index": 0, "embedding": []float64{1, 0}},
+		}})
+	}))
+	defer srv.Close()
+
+	cfg := &config.OpenAIConfig{BaseURL: srv.URL}
+	if _, err := EmbedWithOpenAI(context.Background(), cfg, []string{"a", "b"}); err == nil {
+		t.Error("want an error when a vector is missing")
+	}
+}
+
+func TestEmbedTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cfg := &config.Config{}
+	cfg.LLM.Backend = "ollama"
+	cfg.Ollama.EmbedEndpoint = srv.URL + "/api/embed"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := Embed(ctx, cfg, []string{"a"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want a deadline error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Embed kept waiting past its deadline")
+	}
+}
@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// FewShotExamples picks up to count bullets of the section at placement to
+// show the model as style examples. Bullets closest to where the note goes
+// win (see NearestBullets), and the farthest are dropped until they fit the
+// rough token budget. A maxTokens of 0 means no budget.
+func FewShotExamples(content string, placement []string, count, maxTokens int) []string {
+	for n := count; n > 0; n-- {
+		// A smaller window only loses the farthest bullet
+		picked := NearestBullets(content, placement, n)
+		used := 0
+		for _, b := range picked {
+			used += EstimateTokens(b)
+		}
+		if maxTokens <= 0 || used <= maxTokens {
+			return picked
+		}
+	}
+	return nil
+}
+
+// EstimateTokens is a cheap stand-in for a tokenizer: ~4 characters a token.
+func EstimateTokens(s string) int {
+	return utf8.RuneCountInString(s)/4 + 1
+}
+
+// fewShotMessages primes the conversation with existing notes so the model
+// copies their phrasing.
+func fewShotMessages(examples []string, path string) []ChatMessage {
+	if len(examples) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Here are existing notes from the section %q. ", path)
+	b.WriteString("Match their tone, tense, length and formatting in your answer.\n")
+	for _, ex := range examples {
+		b.WriteString("- " + ex + "\n")
+	}
+
+	return []ChatMessage{
+		{Role: "user", Content: b.String()},
+		{Role: "assistant", Content: "Understood. I'll match that style."},
+	}
+}
@@ -0,0 +1,216 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var removedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+// diffContext is how many unchanged lines are shown around each change.
+const diffContext = 3
+
+// FileDiffModel previews replacing a whole file: the diff between the
+// current and the proposed content, which can still be tweaked in $EDITOR
+// before confirming.
+type FileDiffModel struct {
+	name     string
+	old      []string
+	proposed []string
+	diff     diffView
+	added    int
+	removed  int
+	status   string
+
+	confirmed bool
+}
+
+type draftEditedMsg struct {
+	text string
+	err  error
+}
+
+func NewFileDiffModel(name, old, proposed string) FileDiffModel {
+	return FileDiffModel{
+		name:     name,
+		old:      strings.Split(old, "\n"),
+		proposed: strings.Split(proposed, "\n"),
+	}
+}
+
+func (m FileDiffModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m FileDiffModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.diff.resize(msg.Width, msg.Height-4) // title, summary, blank, help
+		m.render()
+		return m, nil
+
+	case draftEditedMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
+			return m, nil
+		}
+		m.proposed = strings.Split(msg.text, "\n")
+		m.render()
+		m.status = "Draft updated."
+		return m, nil
+
+	case tea.KeyMsg:
+		m.status = ""
+		if action := diffKey(msg.String()); action != diffNone {
+			m.confirmed = action == diffConfirm
+			return m, tea.Quit
+		}
+		switch msg.String() {
+		case "n", "g":
+			m.diff.jump(1)
+			return m, nil
+		case "N", "p":
+			m.diff.jump(-1)
+			return m, nil
+		case "e":
+			return m, m.editDraft()
+		}
+	}
+
+	m.diff, cmd = m.diff.update(msg)
+	return m, cmd
+}
+
+// editDraft opens the proposed content in $EDITOR.
+func (m FileDiffModel) editDraft() tea.Cmd {
+	f, err := os.CreateTemp("", "writeme-draft-*"+filepath.Ext(m.name))
+	if err != nil {
+		return func() tea.Msg { return draftEditedMsg{err: fmt.Errorf("could not create temp file: %w", err)} }
+	}
+	_, err = f.WriteString(strings.Join(m.proposed, "\n"))
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return func() tea.Msg { return draftEditedMsg{err: fmt.Errorf("could not write temp file: %w", err)} }
+	}
+
+	editor := EditorCommand(f.Name())
+	editor.Stdout, editor.Stderr = nil, nil // let Bubble Tea hand over the terminal
+	return tea.ExecProcess(editor, func(err error) tea.Msg {
+		defer os.Remove(f.Name())
+		if err != nil {
+			return draftEditedMsg{err: fmt.Errorf("failed to launch editor (%s): %w", editor.Args[0], err)}
+		}
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			return draftEditedMsg{err: fmt.Errorf("could not read back draft: %w", err)}
+		}
+		return draftEditedMsg{text: string(data)}
+	})
+}
+
+func (m *FileDiffModel) render() {
+	if !m.diff.ready {
+		return
+	}
+	lines, changes := UnifiedDiff(m.old, m.proposed, diffContext)
+
+	m.added, m.removed = 0, 0
+	for _, op := range Diff(m.old, m.proposed) {
+		switch op.Kind {
+		case DiffInsert:
+			m.added++
+		case DiffDelete:
+			m.removed++
+		}
+	}
+	m.diff.setContent(lines, changes)
+}
+
+// UnifiedDiff renders a colored diff of a and b with context lines around
+// each change. It also returns the index of each hunk header.
+func UnifiedDiff(a, b []string, context int) ([]string, []int) {
+	ops := Diff(a, b)
+
+	// Which ops to show: every change plus context around it
+	show := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.Kind == DiffEqual {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(ops)-1, i+context); j++ {
+			show[j] = true
+		}
+	}
+
+	var out []string
+	var hunks []int
+	oldLine, newLine := 1, 1
+	width := len(fmt.Sprint(max(len(a), len(b))))
+	for i, op := range ops {
+		if show[i] {
+			if i == 0 || !show[i-1] {
+				hunks = append(hunks, len(out))
+				out = append(out, gutterStyle.Render(fmt.Sprintf("@@ -%d +%d @@", oldLine, newLine)))
+			}
+			switch op.Kind {
+			case DiffEqual:
+				out = append(out, gutterStyle.Render(fmt.Sprintf("%*d ", width, newLine))+"  "+op.Text)
+			case DiffDelete:
+				out = append(out, gutterStyle.Render(fmt.Sprintf("%*s ", width, ""))+removedStyle.Render("- "+op.Text))
+			case DiffInsert:
+				out = append(out, gutterStyle.Render(fmt.Sprintf("%*d ", width, newLine))+addedStyle.Render("+ "+op.Text))
+			}
+		}
+		switch op.Kind {
+		case DiffEqual:
+			oldLine++
+			newLine++
+		case DiffDelete:
+			oldLine++
+		case DiffInsert:
+			newLine++
+		}
+	}
+	return out, hunks
+}
+
+func (m FileDiffModel) View() string {
+	if !m.diff.ready {
+		return "\n  Loading preview..."
+	}
+
+	var b strings.Builder
+	b.WriteString(m.diff.view(fmt.Sprintf("Rewriting %s: %s %s", m.name, addedStyle.Render(fmt.Sprintf("+%d", m.added)), removedStyle.Render(fmt.Sprintf("-%d", m.removed)))))
+	if m.status != "" {
+		b.WriteString(m.status + "  ")
+	}
+	b.WriteString(helpStyle.Render("Enter/y write · e edit draft · n/N next/previous change · ↑/↓ scroll · q cancel"))
+	return b.String()
+}
+
+// RunFileDiff previews replacing the content of name with proposed. It
+// returns the (possibly edited) content and whether the user confirmed.
+func RunFileDiff(name, old, proposed string) (string, bool, error) {
+	tty, done, err := TerminalInput()
+	if err != nil {
+		return "", false, err
+	}
+	defer done()
+
+	p := tea.NewProgram(NewFileDiffModel(name, old, proposed), tea.WithInput(tty), tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		return "", false, err
+	}
+
+	m := finalModel.(FileDiffModel)
+	return strings.Join(m.proposed, "\n"), m.confirmed, nil
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to a temp file next to path and renames it
+// over path, so nobody ever sees a half-written file. An existing file keeps
+// its permissions (perm is for new files), and a symlink keeps pointing at
+// the file it points at.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	path, perm = writeTarget(path, perm)
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not close temp file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("could not set permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("could not replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// FileWrite is one file for WriteFilesAtomic.
+type FileWrite struct {
+	Path string
+	Data []byte
+	Perm os.FileMode
+}
+
+// WriteFilesAtomic writes several files as one change: all of them are
+// staged in temp files first, and if replacing one fails the files already
+// replaced are put back the way they were.
+func WriteFilesAtomic(writes []FileWrite) error {
+	type staged struct {
+		FileWrite
+		tmp      string
+		original []byte
+		existed  bool
+	}
+
+	var files []staged
+	defer func() {
+		for _, f := range files {
+			os.Remove(f.tmp) // no-op once renamed
+		}
+	}()
+
+	for _, w := range writes {
+		w.Path, w.Perm = writeTarget(w.Path, w.Perm)
+		original, err := os.ReadFile(w.Path)
+		existed := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not read %s: %w", w.Path, err)
+		}
+
+		tmp, err := os.CreateTemp(filepath.Dir(w.Path), "."+filepath.Base(w.Path)+".tmp-*")
+		if err != nil {
+			return fmt.Errorf("could not create temp file: %w", err)
+		}
+		files = append(files, staged{w, tmp.Name(), original, existed})
+
+		_, err = tmp.Write(w.Data)
+		if err == nil {
+			err = tmp.Sync()
+		}
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Chmod(tmp.Name(), w.Perm)
+		}
+		if err != nil {
+			return fmt.Errorf("could not stage %s: %w", w.Path, err)
+		}
+	}
+
+	for i, f := range files {
+		if err := os.Rename(f.tmp, f.Path); err != nil {
+			for _, done := range files[:i] {
+				if done.existed {
+					WriteFileAtomic(done.Path, done.original, done.Perm)
+				} else {
+					os.Remove(done.Path)
+				}
+			}
+			return fmt.Errorf("could not replace %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// writeTarget resolves symlinks in path and returns the permissions to
+// write it with: those of the existing file, or perm for a new one.
+func writeTarget(path string, perm os.FileMode) (string, os.FileMode) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return path, perm
+}
@@ -0,0 +1,285 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// NoteFlowOptions configures the `writeme note` screen.
+type NoteFlowOptions struct {
+	Name    string     // file name shown in the preview
+	Content string     // current file content
+	Notes   []string   // notes to insert; empty means write them in $EDITOR
+	Recent  [][]string // recently used sections, most recent first
+
+	// Placement skips the picker and uses this section (e.g. --last).
+	Placement []string
+
+	// Reword runs notes through the AI for the chosen section. Nil skips
+	// the AI step.
+	Reword func(placement []string, notes []string) ([]string, error)
+
+	// Decorate finishes the notes before the preview (e.g. adds the git
+	// context), so what's confirmed is what gets written. Nil leaves them
+	// as they are.
+	Decorate func(notes []string) ([]string, error)
+}
+
+type flowStage int
+
+const (
+	stagePick flowStage = iota
+	stageCompose
+	stageAI
+	stagePreview
+)
+
+// pickedMsg starts the flow after the picker when the section is known up
+// front.
+type pickedMsg struct{}
+
+type composedMsg struct {
+	notes []string
+	err   error
+}
+
+type rewordedMsg struct {
+	notes []string
+	err   error
+}
+
+// NoteFlowModel is the whole `writeme note` interaction in one program:
+// pick a section, write the note in $EDITOR if none was given, let the AI
+// reword it, then preview and edit the change.
+type NoteFlowModel struct {
+	opts      NoteFlowOptions
+	lines     []string
+	stage     flowStage
+	picker    PickerModel
+	spinner   spinner.Model
+	preview   PreviewModel
+	placement []string
+	notes     []string
+	result    PreviewResult
+	err       error
+	width     int
+	height    int
+}
+
+func NewNoteFlowModel(opts NoteFlowOptions) NoteFlowModel {
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+
+	return NoteFlowModel{
+		opts:    opts,
+		lines:   strings.Split(opts.Content, "\n"),
+		stage:   stagePick,
+		picker:  NewPickerModel(ParseHeadings(opts.Content), opts.Recent).WithName(opts.Name),
+		spinner: sp,
+		notes:   opts.Notes,
+	}
+}
+
+func (m NoteFlowModel) Init() tea.Cmd {
+	if len(m.opts.Placement) > 0 {
+		return func() tea.Msg { return pickedMsg{} }
+	}
+	return m.picker.Init()
+}
+
+func (m NoteFlowModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.picker, _ = m.picker.Update(msg)
+		if m.stage == stagePreview {
+			return m.updatePreview(msg)
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" && m.stage != stagePreview {
+			return m, tea.Quit
+		}
+
+	case pickedMsg:
+		m.placement = m.opts.Placement
+		return m.afterPick()
+
+	case composedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, tea.Quit
+		}
+		if len(msg.notes) == 0 {
+			return m, tea.Quit // empty note: nothing to do
+		}
+		m.notes = msg.notes
+		return m.afterCompose()
+
+	case rewordedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, tea.Quit
+		}
+		return m.startPreview(msg.notes)
+
+	case previewDoneMsg:
+		m.result = msg.result
+		return m, tea.Quit
+
+	case spinner.TickMsg:
+		if m.stage != stageAI {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+
+	switch m.stage {
+	case stagePick:
+		var cmd tea.Cmd
+		m.picker, cmd = m.picker.Update(msg)
+		if m.picker.done {
+			m.placement = m.picker.selected
+			return m.afterPick()
+		}
+		return m, cmd
+	case stagePreview:
+		return m.updatePreview(msg)
+	}
+	return m, nil
+}
+
+func (m NoteFlowModel) updatePreview(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.preview.Update(msg)
+	m.preview = model.(PreviewModel)
+	return m, cmd
+}
+
+// afterPick opens $EDITOR when there's no note yet.
+func (m NoteFlowModel) afterPick() (tea.Model, tea.Cmd) {
+	if len(m.notes) > 0 {
+		return m.afterCompose()
+	}
+	m.stage = stageCompose
+
+	f, err := os.CreateTemp("", "writeme-note-*.md")
+	if err != nil {
+		m.err = fmt.Errorf("could not create temp file: %w", err)
+		return m, tea.Quit
+	}
+	_, err = f.WriteString(NoteTemplate(m.opts.Content, m.placement))
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		m.err = fmt.Errorf("could not write temp file: %w", err)
+		return m, tea.Quit
+	}
+
+	editor := EditorCommand(f.Name())
+	editor.Stdout, editor.Stderr = nil, nil // let Bubble Tea hand over the terminal
+	return m, tea.ExecProcess(editor, func(err error) tea.Msg {
+		defer os.Remove(f.Name())
+		if err != nil {
+			return composedMsg{err: fmt.Errorf("failed to launch editor (%s): %w", editor.Args[0], err)}
+		}
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			return composedMsg{err: fmt.Errorf("could not read back note: %w", err)}
+		}
+		return composedMsg{notes: ParseNoteTemplate(string(data))}
+	})
+}
+
+// afterCompose runs the AI step if there is one.
+func (m NoteFlowModel) afterCompose() (tea.Model, tea.Cmd) {
+	if m.opts.Reword == nil {
+		return m.startPreview(m.notes)
+	}
+	m.stage = stageAI
+
+	reword, placement, notes := m.opts.Reword, m.placement, m.notes
+	return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+		out, err := reword(placement, notes)
+		return rewordedMsg{out, err}
+	})
+}
+
+func (m NoteFlowModel) startPreview(notes []string) (tea.Model, tea.Cmd) {
+	at := InsertionPoint(m.lines, m.placement)
+	if at < 0 {
+		m.err = fmt.Errorf("could not find section %v in %s", m.placement, m.opts.Name)
+		return m, tea.Quit
+	}
+
+	decorate := m.opts.Decorate
+	if decorate == nil {
+		decorate = func(notes []string) ([]string, error) { return notes, nil }
+	}
+	notes, err := decorate(notes)
+	if err != nil {
+		m.err = err
+		return m, tea.Quit
+	}
+
+	m.stage = stagePreview
+	m.preview = NewPreviewModel(m.opts.Name, m.lines, at, notes)
+	m.preview.embedded = true
+	if m.opts.Reword != nil {
+		reword, placement, typed := m.opts.Reword, m.placement, m.notes
+		original, err := decorate(typed)
+		if err != nil {
+			m.err = err
+			return m, tea.Quit
+		}
+		m.preview = m.preview.WithComparison(original, func() ([]string, error) {
+			out, err := reword(placement, typed)
+			if err != nil {
+				return nil, err
+			}
+			return decorate(out)
+		})
+	}
+
+	size := tea.WindowSizeMsg{Width: m.width, Height: m.height}
+	model, _ := m.preview.Update(size)
+	m.preview = model.(PreviewModel)
+	return m, m.preview.Init()
+}
+
+func (m NoteFlowModel) View() string {
+	switch m.stage {
+	case stageCompose:
+		return "\n  Waiting for your editor..."
+	case stageAI:
+		return fmt.Sprintf("\n  %s Asking the AI to rework your note for %s...", m.spinner.View(), strings.Join(m.placement, " > "))
+	case stagePreview:
+		return m.preview.View()
+	default:
+		return m.picker.View()
+	}
+}
+
+// RunNoteFlow runs the note screen and returns what the user confirmed.
+func RunNoteFlow(opts NoteFlowOptions) (PreviewResult, error) {
+	tty, done, err := TerminalInput()
+	if err != nil {
+		return PreviewResult{}, err
+	}
+	defer done()
+
+	p := tea.NewProgram(NewNoteFlowModel(opts), tea.WithInput(tty), tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		return PreviewResult{}, err
+	}
+
+	m := finalModel.(NoteFlowModel)
+	return m.result, m.err
+}
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"strings"
+	"unicode"
+)
+
+// PathSeparator joins heading paths for display and fuzzy matching.
+const PathSeparator = " > "
+
+// FuzzyMatch scores pattern as a case-insensitive subsequence of text. Matches
+// at word starts and runs of consecutive characters score higher. It returns
+// the rune indexes of text that matched.
+func FuzzyMatch(pattern, text string) (int, []int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	score := 0
+	var matched []int
+	pi, last := 0, -2
+	for ti := 0; ti < len(t) && pi < len(p); ti++ {
+		if unicode.ToLower(t[ti]) != p[pi] {
+			continue
+		}
+
+		score++
+		if ti == 0 || isWordBoundary(t[ti-1]) {
+			score += 5
+		}
+		if ti == last+1 {
+			score += 3
+		}
+		if len(matched) > 0 {
+			score -= min(ti-last-1, 3) // small penalty for gaps
+		}
+
+		matched = append(matched, ti)
+		last = ti
+		pi++
+	}
+
+	if pi < len(p) {
+		return 0, nil, false
+	}
+	// Prefer shorter targets when everything else is equal
+	score -= len(t) / 10
+	return score, matched, true
+}
+
+func isWordBoundary(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("-_/.>:()", r)
+}
+
+// FuzzyMatchPath matches a query against a heading path. A query with "/" in
+// it is matched segment by segment against headings in order, so "api/auth"
+// matches "API > Authentication"; without "/" the whole joined path is
+// searched. Matched indexes refer to the path joined with PathSeparator.
+func FuzzyMatchPath(query string, path []string) (int, []int, bool) {
+	joined := strings.Join(path, PathSeparator)
+
+	var segments []string
+	for _, seg := range strings.Split(query, "/") {
+		if seg = strings.TrimSpace(seg); seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	if len(segments) <= 1 {
+		return FuzzyMatch(strings.TrimSpace(query), joined)
+	}
+
+	// Rune offset of each heading in the joined string
+	offsets := make([]int, len(path))
+	off := 0
+	for i, title := range path {
+		offsets[i] = off
+		off += len([]rune(title)) + len([]rune(PathSeparator))
+	}
+
+	total := 0
+	var matched []int
+	h := 0
+	for _, seg := range segments {
+		found := false
+		for ; h < len(path); h++ {
+			score, idx, ok := FuzzyMatch(seg, path[h])
+			if !ok {
+				continue
+			}
+			total += score
+			for _, i := range idx {
+				matched = append(matched, offsets[h]+i)
+			}
+			h++
+			found = true
+			break
+		}
+		if !found {
+			return 0, nil, false
+		}
+	}
+	return total, matched, true
+}
@@ -0,0 +1,152 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Git runs git in the current directory and returns its trimmed stdout.
+func Git(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// GitRevBefore returns the last commit on HEAD made before t, or "" if the
+// history starts after t.
+func GitRevBefore(t time.Time) (string, error) {
+	return Git("rev-list", "-1", "--before="+t.Format(time.RFC3339), "HEAD")
+}
+
+// GitAddedLines returns the line indexes of content (the current text of
+// file) that aren't in file as of rev. An empty rev, or a file that didn't
+// exist yet at rev, makes every line new.
+func GitAddedLines(file, content, rev string) (map[int]bool, error) {
+	var old string
+	if rev != "" {
+		if _, err := Git("rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+			return nil, fmt.Errorf("%q is not a date or a git revision", rev)
+		}
+		// "./" makes the path relative to the current directory, not the repo root
+		shown, err := Git("show", rev+":./"+filepath.ToSlash(file))
+		if err == nil {
+			old = shown
+		}
+	}
+
+	var oldLines []string
+	if old != "" {
+		oldLines = strings.Split(old, "\n")
+	}
+
+	added := map[int]bool{}
+	line := 0
+	for _, op := range Diff(oldLines, strings.Split(content, "\n")) {
+		switch op.Kind {
+		case DiffInsert:
+			added[line] = true
+			line++
+		case DiffEqual:
+			line++
+		}
+	}
+	return added, nil
+}
+
+// NotesCommitPrefix starts the subject of commits made by git.auto_commit.
+const NotesCommitPrefix = "notes: "
+
+// GitFileDirty reports whether file has changes that aren't committed,
+// staged or not. An untracked file isn't dirty: there are no earlier edits
+// to sweep in, GitCommitNotes just adds it.
+func GitFileDirty(file string) (bool, error) {
+	out, err := Git("status", "--porcelain", "--", file)
+	if err != nil {
+		return false, err
+	}
+	return out != "" && !strings.HasPrefix(out, "??"), nil
+}
+
+// GitOnlyTouches reports whether commit rev changes nothing but file.
+func GitOnlyTouches(rev, file string) bool {
+	top, err := Git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(top, abs)
+	if err != nil {
+		return false
+	}
+	changed, err := Git("diff-tree", "--no-commit-id", "--name-only", "-r", "--root", rev)
+	return err == nil && changed == filepath.ToSlash(rel)
+}
+
+// GitCommitNotes commits file, and only file, with message; whatever else
+// is staged stays staged. With amend, a HEAD that is an unpushed notes
+// commit for the same file is amended instead, its subject extended with
+// the new section. It returns the subject it committed with.
+func GitCommitNotes(file, section string, amend bool) (string, error) {
+	subject := NotesCommitPrefix + "add to " + section
+	args := []string{"commit", "--quiet", "--only"}
+
+	if amend && gitCanAmendNotes(file) {
+		last, err := Git("log", "-1", "--format=%s")
+		if err != nil {
+			return "", err
+		}
+		subject = last
+		if !slices.Contains(notesCommitSections(last), section) {
+			subject += ", " + section
+		}
+		args = append(args, "--amend")
+	}
+
+	// --only needs git to know the file; a new notes file isn't yet
+	if out, err := Git("status", "--porcelain", "--", file); err == nil && strings.HasPrefix(out, "??") {
+		if _, err := Git("add", "--", file); err != nil {
+			return "", fmt.Errorf("could not add %s: %w", file, err)
+		}
+	}
+
+	args = append(args, "--message", subject, "--", file)
+	if _, err := Git(args...); err != nil {
+		return "", fmt.Errorf("could not commit %s: %w", file, err)
+	}
+	return subject, nil
+}
+
+// notesCommitSections returns the sections listed in the subject of a notes
+// commit, "notes: add to A > B, C" has "A > B" and "C".
+func notesCommitSections(subject string) []string {
+	return strings.Split(strings.TrimPrefix(subject, NotesCommitPrefix+"add to "), ", ")
+}
+
+// gitCanAmendNotes reports whether HEAD is a notes commit that only touches
+// file and isn't on any remote branch yet.
+func gitCanAmendNotes(file string) bool {
+	last, err := Git("log", "-1", "--format=%s")
+	if err != nil || !strings.HasPrefix(last, NotesCommitPrefix) {
+		return false
+	}
+	if !GitOnlyTouches("HEAD", file) {
+		return false
+	}
+	remote, err := Git("branch", "--remotes", "--contains", "HEAD")
+	return err == nil && remote == ""
+}
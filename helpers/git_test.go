@@ -0,0 +1,129 @@
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// tempRepo makes a git repo with one commit in a temp dir and moves into it.
+func tempRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"config", "user.name", "test"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "--quiet", "--allow-empty", "--message", "init"},
+	} {
+		if _, err := Git(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeNotes(t *testing.T, content string) {
+	if err := os.WriteFile("NOTES.md", []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lastSubject(t *testing.T) string {
+	subject, err := Git("log", "-1", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return subject
+}
+
+func TestGitCommitNotes(t *testing.T) {
+	tempRepo(t)
+
+	// A new notes file is untracked, which isn't dirty
+	writeNotes(t, "# P\n\n## API\n- one\n")
+	if dirty, err := GitFileDirty("NOTES.md"); err != nil || dirty {
+		t.Fatalf("untracked NOTES.md: dirty = %v (%v), want false", dirty, err)
+	}
+	// Something else staged stays out of the commit
+	if err := os.WriteFile("other.txt", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Git("add", "other.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GitCommitNotes("NOTES.md", "P > API", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastSubject(t); got != "notes: add to P > API" {
+		t.Errorf("subject = %q", got)
+	}
+	if !GitOnlyTouches("HEAD", "NOTES.md") {
+		t.Error("the notes commit touches more than NOTES.md")
+	}
+	if staged, _ := Git("diff", "--cached", "--name-only"); staged != "other.txt" {
+		t.Errorf("staged after commit = %q, want other.txt", staged)
+	}
+
+	// Amending with a section that's a prefix of one already listed
+	// still lists it
+	writeNotes(t, "# P\n- top\n\n## API\n- one\n")
+	if _, err := GitCommitNotes("NOTES.md", "P", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastSubject(t); got != "notes: add to P > API, P" {
+		t.Errorf("amended subject = %q", got)
+	}
+	writeNotes(t, "# P\n- top\n\n## API\n- one\n- two\n")
+	if _, err := GitCommitNotes("NOTES.md", "P > API", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastSubject(t); got != "notes: add to P > API, P" {
+		t.Errorf("subject after amending a listed section = %q", got)
+	}
+	if count, _ := Git("rev-list", "--count", "HEAD"); count != "2" {
+		t.Errorf("%s commits, want the notes commit amended", count)
+	}
+
+	// Uncommitted edits make the file dirty
+	writeNotes(t, "# P\n- top\n\n## API\n- one\n- two\n- three\n")
+	if dirty, err := GitFileDirty("NOTES.md"); err != nil || !dirty {
+		t.Fatalf("edited NOTES.md: dirty = %v (%v), want true", dirty, err)
+	}
+}
+
+func TestGitCommitNotesNoAmendAfterPush(t *testing.T) {
+	tempRepo(t)
+	remote := t.TempDir()
+	if _, err := Git("init", "--quiet", "--bare", remote); err != nil {
+		t.Fatal(err)
+	}
+
+	writeNotes(t, "# P\n- one\n")
+	if _, err := GitCommitNotes("NOTES.md", "P", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"remote", "add", "origin", remote},
+		{"push", "--quiet", "origin", "HEAD:main"},
+		{"fetch", "--quiet", "origin"},
+	} {
+		if _, err := Git(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeNotes(t, "# P\n- one\n- two\n")
+	subject, err := GitCommitNotes("NOTES.md", "P", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "notes: add to P" {
+		t.Errorf("subject = %q", subject)
+	}
+	if count, _ := Git("rev-list", "--count", "HEAD"); count != "3" {
+		t.Errorf("%s commits, want a new commit instead of amending the pushed one", count)
+	}
+}
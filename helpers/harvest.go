@@ -0,0 +1,199 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxHarvestSize skips files too big to be source, like generated data.
+const maxHarvestSize = 1 << 20
+
+// harvestComment finds a TODO after a comment marker: //, #, --, ;, /*, a
+// block comment's leading *, <!-- or %. The keyword is upper case and may
+// carry an owner, as in TODO(vishnu): text.
+var harvestComment = regexp.MustCompile(`(?:^|[\s;{}()])(?://+|#+|--+|;+|/\*+|\*+|<!--|%+)\s*(TODO|FIXME|NOTE)\b(?:\([^)]*\))?:?\s*(.*)$`)
+
+// harvestHTMLComment is harvestComment for Markdown and HTML, where # and *
+// start headings and lists rather than comments.
+var harvestHTMLComment = regexp.MustCompile(`<!--\s*(TODO|FIXME|NOTE)\b(?:\([^)]*\))?:?\s*(.*)$`)
+
+// harvestedBullet matches the bullets harvest writes, see Harvested.Note.
+var harvestedBullet = regexp.MustCompile(`^(TODO|FIXME|NOTE): (.*) \[[^\]]*\]\(([^)#]*)(?:#L\d+(?:-L\d+)?)?\)$`)
+
+// Harvested is a TODO-style comment found in the source.
+type Harvested struct {
+	Kind string // TODO, FIXME or NOTE
+	Text string
+	Ref  NoteRef
+}
+
+// Note is the bullet for the comment, e.g.
+// "TODO: retry on 503 [api/client.go:88](api/client.go#L88)".
+func (h Harvested) Note() string {
+	return h.Kind + ": " + h.Text + " " + h.Ref.String()
+}
+
+// Key identifies the comment without its line number, so moving code
+// around doesn't make it look new.
+func (h Harvested) Key() string {
+	return h.Kind + "\x00" + h.Text + "\x00" + h.Ref.Link
+}
+
+// ParseHarvested reads a bullet written by harvest back, with only the
+// Link of its Ref filled in.
+func ParseHarvested(text string) (Harvested, bool) {
+	m := harvestedBullet.FindStringSubmatch(text)
+	if m == nil {
+		return Harvested{}, false
+	}
+	return Harvested{Kind: m[1], Text: m[2], Ref: NoteRef{Link: m[3]}}, true
+}
+
+// HarvestFiles lists the files to scan, relative to the current directory:
+// everything git knows about or would pick up in the whole work tree, so
+// .gitignore is respected. Outside git it walks the current directory,
+// skipping hidden directories.
+func HarvestFiles() ([]string, error) {
+	top, err := Git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return walkFiles(".")
+	}
+	out, err := Git("-C", top, "ls-files", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range strings.Split(out, "\n") {
+		if name == "" {
+			continue
+		}
+		rel, err := filepath.Rel(cwd, filepath.Join(top, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		files = append(files, rel)
+	}
+	return files, nil
+}
+
+func walkFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if (path != root && strings.HasPrefix(d.Name(), ".")) || d.Name() == "node_modules" || d.Name() == "vendor" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// HarvestComments scans files for TODO, FIXME and NOTE comments, skipping
+// notesFile itself, binary files and very large ones. Refs link relative
+// to notesFile.
+func HarvestComments(files []string, notesFile string) ([]Harvested, error) {
+	notesAbs, err := filepath.Abs(notesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Harvested
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err != nil || abs == notesAbs {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			// Deleted but still in the index, a dangling symlink and so on
+			continue
+		}
+		if len(data) > maxHarvestSize || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			continue
+		}
+
+		pattern := harvestComment
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".md", ".markdown", ".html", ".htm":
+			pattern = harvestHTMLComment
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), maxHarvestSize)
+		for line := 1; scanner.Scan(); line++ {
+			m := pattern.FindStringSubmatch(scanner.Text())
+			if m == nil {
+				continue
+			}
+			text := strings.TrimSpace(m[2])
+			text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(text, "*/"), "-->"))
+			if text == "" {
+				continue
+			}
+			ref, err := ParseRef(fmt.Sprintf("%s:%d", file, line), notesFile)
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, Harvested{Kind: m[1], Text: text, Ref: ref})
+		}
+	}
+	return found, nil
+}
+
+// NewHarvested drops the comments already in the notes: harvested before,
+// anywhere in the file, or written as a bullet with exactly the same text.
+// Duplicates within found are dropped too.
+func NewHarvested(content string, found []Harvested) []Harvested {
+	seen := map[string]bool{}
+	for _, n := range AllNotes("", content, nil) {
+		if h, ok := ParseHarvested(n.Text); ok {
+			seen[h.Key()] = true
+		}
+		seen[n.Text] = true
+	}
+
+	var fresh []Harvested
+	for _, h := range found {
+		if seen[h.Key()] || seen[h.Text] || seen[h.Kind+": "+h.Text] {
+			continue
+		}
+		seen[h.Key()] = true
+		fresh = append(fresh, h)
+	}
+	return fresh
+}
+
+// StaleHarvested returns the harvested bullets in content whose comment is
+// no longer in found.
+func StaleHarvested(file, content string, found []Harvested) []SearchHit {
+	current := map[string]bool{}
+	for _, h := range found {
+		current[h.Key()] = true
+	}
+
+	var stale []SearchHit
+	for _, n := range AllNotes(file, content, nil) {
+		if h, ok := ParseHarvested(n.Text); ok && !current[h.Key()] {
+			stale = append(stale, n)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Lines around the part of a git hook that writeme owns, so it can be
+// updated or removed without touching the rest of the hook.
+const (
+	HookBegin = "# >>> writeme >>>"
+	HookEnd   = "# <<< writeme <<<"
+)
+
+// ShellQuote quotes s for a POSIX shell.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// AddHookBlock returns the hook script content with body (lines of shell)
+// between the writeme markers, replacing an earlier block. The block goes
+// right after the shebang so an "exit" further down can't skip it. A new
+// hook gets a #!/bin/sh line; hooks in other languages are refused.
+func AddHookBlock(content, body string) (string, error) {
+	lines := strings.Split(RemoveHookBlock(content), "\n")
+	if strings.TrimSpace(content) == "" {
+		lines = []string{"#!/bin/sh", ""}
+	}
+	if !strings.HasPrefix(lines[0], "#!") {
+		return "", fmt.Errorf("the hook has no #! line, so it's unclear what runs it")
+	}
+	if shell := hookShell(lines[0]); !shellNames[shell] {
+		return "", fmt.Errorf("the hook is a %s script, not a shell script", shell)
+	}
+
+	block := append([]string{HookBegin}, strings.Split(strings.TrimRight(body, "\n"), "\n")...)
+	block = append(block, HookEnd)
+	lines = splice(lines, 1, 1, block)
+	return strings.Join(lines, "\n"), nil
+}
+
+var shellNames = map[string]bool{"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true}
+
+// hookShell returns the program named on a #! line, looking through env.
+func hookShell(shebang string) string {
+	fields := strings.Fields(strings.TrimPrefix(shebang, "#!"))
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	name := filepath.Base(fields[0])
+	if name == "env" && len(fields) > 1 {
+		name = fields[len(fields)-1]
+	}
+	return name
+}
+
+// RemoveHookBlock drops the writeme block from a hook script, if any.
+func RemoveHookBlock(content string) string {
+	lines := strings.Split(content, "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case HookBegin:
+			begin = i
+		case HookEnd:
+			if begin >= 0 {
+				end = i
+			}
+		}
+		if end >= 0 {
+			break
+		}
+	}
+	if begin < 0 || end < 0 {
+		return content
+	}
+	return strings.Join(splice(lines, begin, end+1, nil), "\n")
+}
+
+// HookIsEmpty reports whether a hook script does nothing but start a shell,
+// as is left over after removing the writeme block from a hook writeme made.
+func HookIsEmpty(content string) bool {
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !(i == 0 && strings.HasPrefix(line, "#!")) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package helpers
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"writeme/config"
+)
+
+// embedBatchSize is how many texts go in one embedding request.
+const embedBatchSize = 64
+
+// EmbeddingIndex caches one vector per note, keyed by a hash of the text
+// that was embedded, so only new or changed notes are sent to the backend.
+type EmbeddingIndex struct {
+	Model   string               `json:"model"`
+	Vectors map[string][]float64 `json:"vectors"`
+
+	path string
+}
+
+// LoadEmbeddingIndex reads the index for notesFile from the state dir. The
+// index starts empty if it doesn't exist yet or was built with another model.
+func LoadEmbeddingIndex(notesFile, model string) (*EmbeddingIndex, error) {
+	abs, err := filepath.Abs(notesFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", notesFile, err)
+	}
+	dir, err := config.ResolveStateDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve state dir: %w", err)
+	}
+	sum := sha1.Sum([]byte(abs))
+	path := filepath.Join(dir, "index", hex.EncodeToString(sum[:8])+".json")
+
+	idx := &EmbeddingIndex{Model: model, Vectors: map[string][]float64{}, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read index: %w", err)
+	}
+
+	var stored EmbeddingIndex
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("could not parse index %s: %w", path, err)
+	}
+	if stored.Model == model && stored.Vectors != nil {
+		idx.Vectors = stored.Vectors
+	}
+	return idx, nil
+}
+
+// Reset forgets every cached vector.
+func (idx *EmbeddingIndex) Reset() {
+	idx.Vectors = map[string][]float64{}
+}
+
+// Update embeds the texts that aren't cached yet and drops vectors for texts
+// that are gone, so texts should be every note in the file, not a filtered
+// subset. It returns how many texts were embedded.
+func (idx *EmbeddingIndex) Update(ctx context.Context, cfg *config.Config, texts []string) (int, error) {
+	wanted := map[string]bool{}
+	var missing []string
+	for _, text := range texts {
+		key := contentHash(text)
+		if wanted[key] {
+			continue
+		}
+		wanted[key] = true
+		if _, ok := idx.Vectors[key]; !ok {
+			missing = append(missing, text)
+		}
+	}
+
+	for key := range idx.Vectors {
+		if !wanted[key] {
+			delete(idx.Vectors, key)
+		}
+	}
+
+	for start := 0; start < len(missing); start += embedBatchSize {
+		batch := missing[start:min(start+embedBatchSize, len(missing))]
+		vectors, err := Embed(ctx, cfg, batch)
+		if err != nil {
+			return start, fmt.Errorf("could not embed notes: %w", err)
+		}
+		for i, text := range batch {
+			idx.Vectors[contentHash(text)] = vectors[i]
+		}
+	}
+	return len(missing), nil
+}
+
+// Vector returns the cached vector for text.
+func (idx *EmbeddingIndex) Vector(text string) ([]float64, bool) {
+	v, ok := idx.Vectors[contentHash(text)]
+	return v, ok
+}
+
+// Save writes the index back to the state dir.
+func (idx *EmbeddingIndex) Save() error {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0755); err != nil {
+		return fmt.Errorf("could not create index directory: %w", err)
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("could not encode index: %w", err)
+	}
+	return WriteFileAtomic(idx.path, data, 0644)
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// EmbeddingText is what gets embedded for a note: its section path gives
+// short bullets some context.
+func EmbeddingText(hit SearchHit) string {
+	return strings.Join(hit.Section, PathSeparator) + ": " + hit.Text
+}
+
+// CosineSimilarity of two vectors; 0 if either is empty or they differ in
+// length.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// SemanticSearch ranks notes by similarity to query and returns the top k
+// (all of them for k=0). The index is brought up to date with notes first,
+// embedding only what changed, so pass every note of the file and filter
+// the result.
+func SemanticSearch(ctx context.Context, cfg *config.Config, idx *EmbeddingIndex, notes []SearchHit, query string, k int) ([]SearchHit, error) {
+	texts := make([]string, len(notes))
+	for i, n := range notes {
+		texts[i] = EmbeddingText(n)
+	}
+	if _, err := idx.Update(ctx, cfg, texts); err != nil {
+		return nil, err
+	}
+
+	qv, err := Embed(ctx, cfg, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("could not embed query: %w", err)
+	}
+
+	ranked := make([]SearchHit, 0, len(notes))
+	for i, n := range notes {
+		v, ok := idx.Vector(texts[i])
+		if !ok {
+			continue
+		}
+		n.Similarity = CosineSimilarity(qv[0], v)
+		ranked = append(ranked, n)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Similarity > ranked[j].Similarity })
+
+	if k > 0 && len(ranked) > k {
+		ranked = ranked[:k]
+	}
+	return ranked, nil
+}
@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"writeme/config"
+)
+
+// stubEmbedder serves Ollama's /api/embed, recording every text it's asked
+// to embed.
+func stubEmbedder(t *testing.T, embedded *[]string) *config.Config {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("bad request: %v", err)
+		}
+		*embedded = append(*embedded, req.Input...)
+		vectors := make([][]float64, len(req.Input))
+		for i, text := range req.Input {
+			vectors[i] = []float64{float64(len(text)), 1}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": vectors})
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := &config.Config{}
+	cfg.LLM.Backend = "ollama"
+	cfg.Ollama.EmbedEndpoint = srv.URL + "/api/embed"
+	return cfg
+}
+
+func TestEmbeddingIndexUpdate(t *testing.T) {
+	t.Setenv("WRITEME_STATE_DIR", t.TempDir())
+	var embedded []string
+	cfg := stubEmbedder(t, &embedded)
+
+	idx, err := LoadEmbeddingIndex("NOTES.md", "ollama:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := []string{"API: tokens expire", "API: keys rotate", "Ops: deploy on friday"}
+	if n, err := idx.Update(context.Background(), cfg, texts); err != nil || n != 3 {
+		t.Fatalf("first Update embedded %d (%v), want 3", n, err)
+	}
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh load finds all of them cached
+	embedded = nil
+	idx, err = LoadEmbeddingIndex("NOTES.md", "ollama:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := idx.Update(context.Background(), cfg, texts); err != nil || n != 0 || len(embedded) != 0 {
+		t.Fatalf("second Update embedded %d %q (%v), want none", n, embedded, err)
+	}
+
+	// Only the changed note is embedded again, and the old one is dropped
+	texts[1] = "API: keys rotate weekly"
+	if n, err := idx.Update(context.Background(), cfg, texts); err != nil || n != 1 || len(embedded) != 1 || embedded[0] != texts[1] {
+		t.Fatalf("Update after an edit embedded %d %q (%v), want just %q", n, embedded, err, texts[1])
+	}
+	if _, ok := idx.Vector("API: keys rotate"); ok {
+		t.Error("the old version of the edited note is still cached")
+	}
+	for _, text := range texts {
+		if _, ok := idx.Vector(text); !ok {
+			t.Errorf("%q is not cached", text)
+		}
+	}
+}
+
+func TestSemanticSearchKeepsWholeFile(t *testing.T) {
+	t.Setenv("WRITEME_STATE_DIR", t.TempDir())
+	var embedded []string
+	cfg := stubEmbedder(t, &embedded)
+
+	content := "# Proj\n\n## API\n- tokens expire\n- keys rotate\n\n## Ops\n- deploy on friday\n"
+	notes := AllNotes("NOTES.md", content, nil)
+	idx, err := LoadEmbeddingIndex("NOTES.md", "ollama:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SemanticSearch(context.Background(), cfg, idx, notes, "tokens", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Searching again, say with --in Ops, only embeds the query
+	embedded = nil
+	ranked, err := SemanticSearch(context.Background(), cfg, idx, notes, "deploys", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embedded) != 1 || embedded[0] != "deploys" {
+		t.Errorf("second search embedded %q, want only the query", embedded)
+	}
+	if len(ranked) != len(notes) {
+		t.Errorf("got %d ranked notes, want %d", len(ranked), len(notes))
+	}
+	if len(idx.Vectors) != len(notes) {
+		t.Errorf("index has %d vectors, want %d", len(idx.Vectors), len(notes))
+	}
+}
@@ -1,83 +1,438 @@
 package helpers
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
-func InsertNote(content string, placement []string, note string) (string, int) {
+// InsertNote adds notes as consecutive bullets under placement and returns
+// the new content plus the line index of the first inserted bullet (-1 if
+// the section wasn't found).
+func InsertNote(content string, placement []string, notes []string) (string, int) {
 	lines := strings.Split(content, "\n")
-	var newLines []string
-	var currentPath []string
 
-	insertAtIndex := -1
+	at := InsertionPoint(lines, placement)
+	if at < 0 {
+		return content, -1
+	}
 
-	i := 0
-	for i < len(lines) {
-		line := lines[i]
+	newLines, insertAt := SpliceNotes(lines, at, notes)
+	return strings.Join(newLines, "\n"), insertAt
+}
 
-		// Update path
-		if strings.HasPrefix(line, "#") {
-			level := 0
-			for level < len(line) && line[level] == '#' {
-				level++
+// InsertionPoint returns the line index new bullets for placement go before:
+// right after the section's last bullet, or right under its heading if it
+// has none. It returns -1 if there's no such section.
+func InsertionPoint(lines []string, placement []string) int {
+	for _, h := range HeadingLines(lines) {
+		if SamePath(HeadingPathAt(lines, h), placement) {
+			return sectionInsertionPoint(lines, h)
+		}
+	}
+	return -1
+}
+
+// ReapplyInsertion moves an insertion planned at line at of base over to
+// latest, a newer version of the same file: it goes after the same line, as
+// long as that line is still there and still in section. ok is false when
+// there's no safe spot, e.g. the section was rewritten.
+func ReapplyInsertion(base, latest string, at int, section []string) (int, bool) {
+	oldLines, newLines := strings.Split(base, "\n"), strings.Split(latest, "\n")
+
+	// Where each line of base that survived ended up in latest
+	kept := map[int]int{}
+	i, j := 0, 0
+	for _, op := range Diff(oldLines, newLines) {
+		switch op.Kind {
+		case DiffEqual:
+			kept[i] = j
+			i++
+			j++
+		case DiffDelete:
+			i++
+		case DiffInsert:
+			j++
+		}
+	}
+
+	if at == 0 {
+		return 0, len(section) == 0
+	}
+	prev, ok := kept[at-1]
+	if !ok || !SamePath(HeadingPathAt(newLines, prev), section) {
+		return -1, false
+	}
+	return prev + 1, true
+}
+
+// EnsureSection finds the section matching in ("Log/Commits" style, see
+// FindSection) and creates it if there's none: at the end of its parent
+// section, or under the top-level heading for a single title. It returns
+// the possibly updated content and the section's full heading path.
+func EnsureSection(content string, in []string) (string, []string, error) {
+	lines := strings.Split(content, "\n")
+	h, err := FindSection(lines, in)
+	if err == nil {
+		return content, HeadingPathAt(lines, h), nil
+	}
+	if !errors.Is(err, ErrNoSection) {
+		return "", nil, err
+	}
+
+	parent := -1
+	if len(in) > 1 {
+		content, _, err = EnsureSection(content, in[:len(in)-1])
+		if err != nil {
+			return "", nil, err
+		}
+		lines = strings.Split(content, "\n")
+		if parent, err = FindSection(lines, in[:len(in)-1]); err != nil {
+			return "", nil, err
+		}
+	} else if heads := HeadingLines(lines); len(heads) > 0 {
+		if level, _, _ := parseHeading(lines[heads[0]]); level == 1 {
+			parent = heads[0]
+		}
+	}
+
+	level, at := 1, len(lines)
+	if parent >= 0 {
+		heads := HeadingLines(lines)
+		for k, h := range heads {
+			if h == parent {
+				at = sectionEnd(lines, heads, k)
 			}
+		}
+		level, _, _ = parseHeading(lines[parent])
+		level++
+	}
+	for at > 0 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+
+	block := []string{"", strings.Repeat("#", level) + " " + in[len(in)-1]}
+	if at == 0 {
+		block = block[1:]
+	}
+	if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		block = append(block, "")
+	}
+	h = at
+	if block[0] == "" {
+		h++
+	}
+	lines = splice(lines, at, at, block)
+	return strings.Join(lines, "\n"), HeadingPathAt(lines, h), nil
+}
+
+// sectionInsertionPoint is InsertionPoint for the heading on line h. In a
+// section of paragraphs without a list, that's after the last paragraph.
+func sectionInsertionPoint(lines []string, h int) int {
+	insertAt := h + 1
+	foundBullet := false
+	lastText := -1
+
+	end := sectionEndAt(lines, h)
+	for j := h + 1; j < end; j++ {
+		nextLine := strings.TrimSpace(lines[j])
+		if listItem.MatchString(nextLine) {
+			foundBullet = true
+			insertAt = j + 1
+		} else if foundBullet && nextLine != "" && isIndented(lines[j]) && insertAt == j {
+			// Continuation line of the last bullet — keep it attached
+			insertAt = j + 1
+		}
+		if nextLine != "" {
+			lastText = j
+		}
+	}
 
-			title := strings.TrimSpace(line[level+1:])
+	if !foundBullet && lastText >= 0 {
+		return lastText + 1
+	}
+	return insertAt
+}
+
+// sectionEndAt returns the line of the first heading after line h, or
+// len(lines).
+func sectionEndAt(lines []string, h int) int {
+	flags := headingFlags(lines)
+	for j := h + 1; j < len(lines); j++ {
+		if flags[j] {
+			return j
+		}
+	}
+	return len(lines)
+}
+
+// ErrNoSection is returned by FindSection when nothing matches.
+var ErrNoSection = errors.New("no section matches")
+
+// FindSection returns the line of the heading matching in ("Design/API"
+// style, see InSection) whose title is the last part of in. It fails if
+// there's no such heading or more than one.
+func FindSection(lines []string, in []string) (int, error) {
+	var found []int
+	for _, h := range HeadingLines(lines) {
+		path := HeadingPathAt(lines, h)
+		if InSection(path, in) && strings.EqualFold(path[len(path)-1], in[len(in)-1]) {
+			found = append(found, h)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return -1, fmt.Errorf("%w %q", ErrNoSection, strings.Join(in, "/"))
+	case 1:
+		return found[0], nil
+	default:
+		var paths []string
+		for _, h := range found {
+			paths = append(paths, strings.Join(HeadingPathAt(lines, h), PathSeparator))
+		}
+		return -1, fmt.Errorf("%q matches more than one section: %s", strings.Join(in, "/"), strings.Join(paths, ", "))
+	}
+}
 
-			if level <= len(currentPath) {
-				currentPath = currentPath[:level-1]
+// splice replaces lines[from:to] with repl.
+func splice(lines []string, from, to int, repl []string) []string {
+	out := make([]string, 0, len(lines)-(to-from)+len(repl))
+	out = append(out, lines[:from]...)
+	out = append(out, repl...)
+	return append(out, lines[to:]...)
+}
+
+// paragraphSection reports whether the section around line at is written in
+// paragraphs: it has text but no list items, so notes shouldn't be bullets.
+func paragraphSection(lines []string, at int) bool {
+	h := -1
+	for _, i := range HeadingLines(lines) {
+		if i >= at {
+			break
+		}
+		h = i
+	}
+
+	hasText := false
+	end := sectionEndAt(lines, max(h, 0))
+	for j := h + 1; j < end; j++ {
+		line := strings.TrimSpace(lines[j])
+		switch {
+		case line == "":
+		case listItem.MatchString(line):
+			return false
+		default:
+			hasText = true
+		}
+	}
+	return hasText
+}
+
+var listItem = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s`)
+
+// listItemMarker captures a list item's marker: "-", "*", "+", or the
+// number and "." or ")" of a numbered one.
+var listItemMarker = regexp.MustCompile(`^(?:([-*+])|(\d+)([.)]))\s+`)
+
+// listMarkerAt returns the marker of the list that notes inserted at line
+// at join: that of the item before them in the section, or the one after.
+// It's "-" when there's no list. For numbered lists it's the number before
+// the notes, see formatItems.
+func listMarkerAt(lines []string, at int) string {
+	flags := headingFlags(lines)
+	for j := at - 1; j >= 0 && !flags[j]; j-- {
+		if m := listItemMarker.FindString(lines[j]); m != "" {
+			return strings.TrimSpace(m)
+		}
+	}
+	if at < len(lines) {
+		if m := listItemMarker.FindStringSubmatch(lines[at]); m != nil {
+			if m[2] != "" {
+				// Count on from the number before it
+				var n int
+				fmt.Sscan(m[2], &n)
+				return fmt.Sprintf("%d%s", n-1, m[3])
 			}
-			currentPath = append(currentPath, title)
-		}
-
-		newLines = append(newLines, line)
-
-		if samePath(currentPath, placement) {
-			insertAt := len(newLines)
-			foundBullet := false
-
-			j := i + 1
-			for j < len(lines) {
-				nextLine := strings.TrimSpace(lines[j])
-				if strings.HasPrefix(nextLine, "#") {
-					break
-				}
-				if strings.HasPrefix(nextLine, "-") {
-					foundBullet = true
-					insertAt = len(newLines) + 1
-				}
-				newLines = append(newLines, lines[j])
-				i++
-				j++
+			return m[1]
+		}
+	}
+	return "-"
+}
+
+// SpliceNotes inserts notes before lines[at]: as bullets, or as paragraphs
+// when the section has no list. A blank line is added after them when
+// they'd otherwise run into a paragraph or heading. It returns the new lines
+// and the index of the first inserted line.
+func SpliceNotes(lines []string, at int, notes []string) ([]string, int) {
+	var block []string
+	if paragraphSection(lines, at) {
+		for _, note := range notes {
+			block = append(block, "")
+			block = append(block, strings.Split(strings.TrimRight(note, "\n"), "\n")...)
+		}
+		if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+			block = append(block, "")
+		}
+	} else {
+		block = formatItems(notes, listMarkerAt(lines, at))
+		if at < len(lines) {
+			next := strings.TrimSpace(lines[at])
+			if next != "" && !listItem.MatchString(next) {
+				block = append(block, "")
 			}
+		}
+	}
+
+	newLines := make([]string, 0, len(lines)+len(block))
+	newLines = append(newLines, lines[:at]...)
+	newLines = append(newLines, block...)
+	newLines = append(newLines, lines[at:]...)
+	return newLines, at
+}
 
-			if !foundBullet {
-				if len(newLines) == 0 || strings.TrimSpace(newLines[len(newLines)-1]) != "" {
-					newLines = append(newLines, "")
-				}
+// HeadingLines returns the indexes of all heading lines.
+func HeadingLines(lines []string) []int {
+	var idx []int
+	for i, ok := range headingFlags(lines) {
+		if ok {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// headingFlags marks which lines are real headings. "# " inside fenced code
+// (usually a shell comment) or inside an HTML block, like the centered
+// logo and badges at the top of many READMEs, doesn't count.
+func headingFlags(lines []string) []bool {
+	flags := make([]bool, len(lines))
+	fence := ""
+	inHTML, inComment := false, false
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
 			}
+		case inComment:
+			inComment = !strings.Contains(trimmed, "-->")
+		case inHTML:
+			inHTML = trimmed != ""
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case strings.HasPrefix(trimmed, "<!--"):
+			inComment = !strings.Contains(trimmed, "-->")
+		case strings.HasPrefix(trimmed, "<") && !isIndented(line):
+			inHTML = true
+		default:
+			_, _, flags[i] = parseHeading(line)
+		}
+	}
+	return flags
+}
 
-			noteLine := fmt.Sprintf("- %s", note)
-			newLines = append(newLines[:insertAt], append([]string{noteLine}, newLines[insertAt:]...)...)
+// HeadingPathAt returns the heading path of the section that line i is in
+// (including line i itself if it's a heading).
+func HeadingPathAt(lines []string, i int) []string {
+	var path []string
+	walkHeadings(lines, i, func(_, depth, _ int, title string) {
+		path = append(path[:depth], title)
+	})
+	return path
+}
 
-			insertAtIndex = insertAt
+// walkHeadings calls visit for each heading up to line last, with how many
+// headings it's nested under. A heading nests under the nearest earlier one
+// of a lower level, so "#" then "###" is one deep, not two. ParseHeadings
+// and HeadingPathAt both nest this way, or sections from one couldn't be
+// found with the other.
+func walkHeadings(lines []string, last int, visit func(h, depth, level int, title string)) {
+	var open []int // levels of the enclosing headings, outermost first
+	for _, h := range HeadingLines(lines) {
+		if h > last {
+			break
+		}
+		level, title, _ := parseHeading(lines[h])
+		for len(open) > 0 && open[len(open)-1] >= level {
+			open = open[:len(open)-1]
+		}
+		visit(h, len(open), level, title)
+		open = append(open, level)
+	}
+}
+
+// FormatNotes renders notes as Markdown bullets. Extra lines in a note become
+// indented continuation lines so they stay part of the same bullet.
+func FormatNotes(notes []string) []string {
+	return formatItems(notes, "-")
+}
+
+// formatItems is FormatNotes for a list using marker, as returned by
+// listMarkerAt. A numbered marker is the item before the notes, so they
+// carry on counting from it.
+func formatItems(notes []string, marker string) []string {
+	m := listItemMarker.FindStringSubmatch(marker + " ")
+	n := 0
+	if m != nil && m[2] != "" {
+		fmt.Sscan(m[2], &n)
+	}
 
-			// 🔑 After inserting, add the remaining lines!
-			for k := i + 1; k < len(lines); k++ {
-				newLines = append(newLines, lines[k])
+	var lines []string
+	for _, note := range notes {
+		prefix := marker
+		if m != nil && m[2] != "" {
+			n++
+			prefix = fmt.Sprintf("%d%s", n, m[3])
+		}
+		indent := strings.Repeat(" ", len(prefix)+1)
+		for i, line := range strings.Split(strings.TrimRight(note, "\n"), "\n") {
+			switch {
+			case i == 0:
+				lines = append(lines, prefix+" "+line)
+			case strings.TrimSpace(line) == "":
+				lines = append(lines, "")
+			default:
+				lines = append(lines, indent+line)
 			}
+		}
+	}
+	return lines
+}
 
-			break // done inserting
+// ParseNotes is the reverse of FormatNotes: every unindented "- " line starts
+// a new note and everything else continues the note before it.
+func ParseNotes(text string) []string {
+	var notes []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "- ") || len(notes) == 0 {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			notes = append(notes, strings.TrimPrefix(line, "- "))
+			continue
 		}
+		notes[len(notes)-1] += "\n" + strings.TrimPrefix(line, "  ")
+	}
 
-		i++
+	var cleaned []string
+	for _, note := range notes {
+		if note = strings.TrimSpace(note); note != "" {
+			cleaned = append(cleaned, note)
+		}
 	}
+	return cleaned
+}
 
-	return strings.Join(newLines, "\n"), insertAtIndex
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
 }
 
-func samePath(a, b []string) bool {
+// SamePath reports whether two heading paths are the same.
+func SamePath(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
 	}
@@ -88,3 +443,114 @@ func samePath(a, b []string) bool {
 	}
 	return true
 }
+
+// SectionBullets returns the bullets directly under the heading at placement,
+// without their list marker. Continuation lines stay attached to their bullet;
+// bullets in subsections aren't included.
+func SectionBullets(content string, placement []string) []string {
+	lines := strings.Split(content, "\n")
+
+	var bullets []string
+	for _, h := range HeadingLines(lines) {
+		if !SamePath(HeadingPathAt(lines, h), placement) {
+			continue
+		}
+		for _, item := range SectionItems(lines, h) {
+			bullets = append(bullets, item.Text)
+		}
+		break
+	}
+	return bullets
+}
+
+// NearestBullets returns up to n bullets of the section at placement,
+// those closest to where new notes go, in document order.
+func NearestBullets(content string, placement []string, n int) []string {
+	lines := strings.Split(content, "\n")
+	at := InsertionPoint(lines, placement)
+	if at < 0 || n <= 0 {
+		return nil
+	}
+
+	var items []SectionItem
+	for _, h := range HeadingLines(lines) {
+		if SamePath(HeadingPathAt(lines, h), placement) {
+			items = SectionItems(lines, h)
+			break
+		}
+	}
+
+	// Bullets sit on one side of at or the other, so the nearest ones are
+	// a window around it
+	k := 0
+	for k < len(items) && items[k].Start < at {
+		k++
+	}
+	from, to := k, k
+	for to-from < n && (from > 0 || to < len(items)) {
+		if from > 0 && (to == len(items) || at-items[from-1].End <= items[to].Start-at) {
+			from--
+		} else {
+			to++
+		}
+	}
+
+	var bullets []string
+	for _, item := range items[from:to] {
+		bullets = append(bullets, item.Text)
+	}
+	return bullets
+}
+
+// SectionItem is a top-level bullet in a section, continuation lines
+// included.
+type SectionItem struct {
+	Start, End int    // the bullet is lines[Start:End]
+	Text       string // without the marker, continuation lines joined with "\n"
+}
+
+// SectionItems returns the top-level bullets under the heading on line h, up
+// to the next heading. Paragraphs between bullets are skipped.
+func SectionItems(lines []string, h int) []SectionItem {
+	var items []SectionItem
+	inBullet := false
+	indent := ""
+
+	end := sectionEndAt(lines, h)
+	for j := h + 1; j < end; j++ {
+		line := lines[j]
+
+		switch {
+		case listItem.MatchString(line):
+			marker := listItemMarker.FindString(line)
+			items = append(items, SectionItem{Start: j, End: j + 1, Text: strings.TrimSpace(line[len(marker):])})
+			inBullet = true
+			// Continuation lines line up with the text, "1. " is 3 wide
+			indent = strings.Repeat(" ", len(strings.TrimSpace(marker))+1)
+		case inBullet && isIndented(line) && strings.TrimSpace(line) != "":
+			last := &items[len(items)-1]
+			if strings.HasPrefix(line, indent) {
+				line = line[len(indent):]
+			} else {
+				line = strings.TrimPrefix(line, "  ")
+			}
+			last.Text += "\n" + line
+			last.End = j + 1
+		default:
+			inBullet = false
+		}
+	}
+	return items
+}
+
+// parseHeading returns the level and title of a "## title" line.
+func parseHeading(line string) (int, string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || len(line) <= level || line[level] != ' ' {
+		return 0, "", false
+	}
+	return level, strings.TrimSpace(line[level+1:]), true
+}
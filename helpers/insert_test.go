@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSkippedHeadingLevels(t *testing.T) {
+	content := "# Proj\n\n### Setup\n- install\n\n### Usage\n- run it\n\n## Later\n- more\n"
+	lines := strings.Split(content, "\n")
+
+	// The picker lists the sections ParseHeadings finds; each must be one
+	// InsertionPoint can find too
+	want := [][]string{{"Proj"}, {"Proj", "Setup"}, {"Proj", "Usage"}, {"Proj", "Later"}}
+	got := AllSectionPaths(ParseHeadings(content))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseHeadings paths = %q, want %q", got, want)
+	}
+	for _, path := range got {
+		if at := InsertionPoint(lines, path); at < 0 {
+			t.Errorf("InsertionPoint(%q) found no section", path)
+		}
+	}
+
+	if got := HeadingPathAt(lines, 6); !reflect.DeepEqual(got, []string{"Proj", "Usage"}) {
+		t.Errorf("HeadingPathAt(### Usage) = %q, want Proj > Usage", got)
+	}
+}
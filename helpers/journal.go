@@ -0,0 +1,182 @@
+package helpers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"writeme/config"
+)
+
+// JournalSize is how many edits per file are kept for undo.
+const JournalSize = 20
+
+// ErrJournal wraps errors from recording an edit that was written fine.
+var ErrJournal = errors.New("could not record the change for undo")
+
+// JournalEntry is one edit writeme made to a file. Before and After are
+// content hashes; the contents are kept next to the journal.
+type JournalEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"` // e.g. "note", "harvest"
+	Before string    `json:"before"`
+	After  string    `json:"after"`
+	// Created is set when the file didn't exist before the edit, so undo
+	// removes it rather than leave an empty file
+	Created bool `json:"created,omitempty"`
+}
+
+// Journal is the recent edit history of one file, for writeme undo/redo.
+// Like ProjectState it lives in the state dir, keyed by the file's absolute
+// path.
+type Journal struct {
+	File    string         `json:"file"`
+	Entries []JournalEntry `json:"entries"`
+	Undone  int            `json:"undone"` // entries at the end that were undone
+
+	dir string // journal.json and the contents
+}
+
+// LoadJournal reads the journal for file. A missing journal just means no
+// edits yet.
+func LoadJournal(file string) (*Journal, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", file, err)
+	}
+	stateDir, err := config.ResolveStateDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve state dir: %w", err)
+	}
+	sum := sha1.Sum([]byte(abs))
+	dir := filepath.Join(stateDir, "journal", hex.EncodeToString(sum[:8]))
+
+	j := &Journal{File: abs, dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, "journal.json"))
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read journal: %w", err)
+	}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("could not parse journal in %s: %w", dir, err)
+	}
+	return j, nil
+}
+
+// Record adds an edit from before to after, dropping whatever was undone
+// (like any editor, a new edit ends the redo history) and the oldest edits
+// past the size limit, then saves. A nil before means the edit created the
+// file.
+func (j *Journal) Record(action string, before, after []byte) error {
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return fmt.Errorf("could not create journal dir: %w", err)
+	}
+	entry := JournalEntry{Time: time.Now(), Action: action, Before: contentHash(string(before)), After: contentHash(string(after)), Created: before == nil}
+	for hash, data := range map[string][]byte{entry.Before: before, entry.After: after} {
+		path := filepath.Join(j.dir, hash)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := WriteFileAtomic(path, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	j.Entries = append(j.Entries[:len(j.Entries)-j.Undone], entry)
+	j.Undone = 0
+	if len(j.Entries) > JournalSize {
+		j.Entries = j.Entries[len(j.Entries)-JournalSize:]
+	}
+	return j.Save()
+}
+
+// Undo steps back one edit. current is the file as it is now: it has to be
+// what the edit left, or the undo would throw away changes made since. The
+// returned content is what the file should be set to, or, if the entry
+// Created it, the file should be removed; Save once it is.
+func (j *Journal) Undo(current []byte) (JournalEntry, []byte, error) {
+	if j.Undone == len(j.Entries) {
+		return JournalEntry{}, nil, fmt.Errorf("nothing to undo")
+	}
+	e := j.Entries[len(j.Entries)-j.Undone-1]
+	if contentHash(string(current)) != e.After {
+		return JournalEntry{}, nil, fmt.Errorf("%s was changed since writeme's last edit (%s); undoing would lose that", filepath.Base(j.File), e.Action)
+	}
+	data, err := os.ReadFile(filepath.Join(j.dir, e.Before))
+	if err != nil {
+		return JournalEntry{}, nil, fmt.Errorf("could not read the earlier version: %w", err)
+	}
+	j.Undone++
+	return e, data, nil
+}
+
+// Redo re-applies the last undone edit, with the same check as Undo.
+func (j *Journal) Redo(current []byte) (JournalEntry, []byte, error) {
+	if j.Undone == 0 {
+		return JournalEntry{}, nil, fmt.Errorf("nothing to redo")
+	}
+	e := j.Entries[len(j.Entries)-j.Undone]
+	if contentHash(string(current)) != e.Before {
+		return JournalEntry{}, nil, fmt.Errorf("%s was changed since the undo; redoing would lose that", filepath.Base(j.File))
+	}
+	data, err := os.ReadFile(filepath.Join(j.dir, e.After))
+	if err != nil {
+		return JournalEntry{}, nil, fmt.Errorf("could not read the later version: %w", err)
+	}
+	j.Undone--
+	return e, data, nil
+}
+
+// Save writes the journal and removes contents no edit refers to anymore.
+func (j *Journal) Save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode journal: %w", err)
+	}
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return fmt.Errorf("could not create journal dir: %w", err)
+	}
+	if err := WriteFileAtomic(filepath.Join(j.dir, "journal.json"), data, 0644); err != nil {
+		return err
+	}
+
+	used := map[string]bool{"journal.json": true}
+	for _, e := range j.Entries {
+		used[e.Before], used[e.After] = true, true
+	}
+	names, _ := os.ReadDir(j.dir)
+	for _, n := range names {
+		if !used[n.Name()] {
+			os.Remove(filepath.Join(j.dir, n.Name()))
+		}
+	}
+	return nil
+}
+
+// SameContent compares file contents by hash, as the journal does.
+func SameContent(data []byte, content string) bool {
+	return contentHash(string(data)) == contentHash(content)
+}
+
+// RecordEdit journals an edit to path that was already written, for writes
+// that can't go through UpdateFile. before is nil if the file didn't exist.
+// Errors wrap ErrJournal.
+func RecordEdit(path, action string, before, after []byte) error {
+	if string(before) == string(after) {
+		return nil
+	}
+	j, err := LoadJournal(path)
+	if err == nil {
+		err = j.Record(action, before, after)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrJournal, err)
+	}
+	return nil
+}
@@ -2,33 +2,102 @@ package helpers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"writeme/config"
 )
 
-// This is your smart wrapper.
-func RewordNote(cfg *config.Config, note string) (string, error) {
+// ChatMessage is a single message in a chat completion request. Both Ollama
+// and OpenAI use the same shape.
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// This is your smart wrapper. It runs the note through mode and returns the
+// bullets to insert, shaped by the mode's output contract. ctx bounds the
+// call to the backend.
+func RewordNote(ctx context.Context, cfg *config.Config, mode Mode, data PromptData) ([]string, error) {
+	systemTmpl := mode.SystemPrompt
+	if systemTmpl == "" {
+		systemTmpl = systemPrompt(cfg)
+	}
+	system, err := RenderPrompt(systemTmpl, data)
+	if err != nil {
+		return nil, err
+	}
+
+	userTmpl := mode.UserPrompt
+	if userTmpl == "" && mode.SystemPrompt != "" {
+		userTmpl = modeUserPrompt
+	}
+	if userTmpl == "" {
+		userTmpl = cfg.LLM.UserPrompt
+	}
+	if userTmpl == "" {
+		userTmpl = DefaultUserPrompt
+	}
+	user, err := RenderPrompt(userTmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	// A one-line contract would flatten a multi-line note, so ask for the
+	// lines to be kept and take the whole reply
+	multiline := mode.Output == OutputLine && strings.Contains(strings.TrimSpace(data.Note), "\n")
+	if multiline {
+		user += "\n\n" + multilineHint
+	}
+
+	messages := []ChatMessage{{Role: "system", Content: system}}
+	examples := FewShotExamples(data.content, data.Section, cfg.LLM.FewShot.Count, cfg.LLM.FewShot.MaxTokens)
+	messages = append(messages, fewShotMessages(examples, data.Path())...)
+	messages = append(messages, ChatMessage{Role: "user", Content: user})
+
+	reply, err := ChatContext(ctx, cfg, messages)
+	if err != nil {
+		return nil, err
+	}
+	if multiline {
+		return parseMultiline(reply)
+	}
+	return ParseModeOutput(mode.Output, reply)
+}
+
+// Chat sends messages to whichever backend the config selects.
+func Chat(cfg *config.Config, messages []ChatMessage) (string, error) {
+	return ChatContext(context.Background(), cfg, messages)
+}
+
+// ChatContext is Chat with a context, e.g. to give up after a timeout.
+func ChatContext(ctx context.Context, cfg *config.Config, messages []ChatMessage) (string, error) {
 	switch cfg.LLM.Backend {
 	case "ollama":
-		return RewordNoteWithOllama(&cfg.Ollama, note)
+		return ChatWithOllama(ctx, &cfg.Ollama, messages)
 	case "openai":
-		return RewordNoteWithOpenAI(&cfg.OpenAI, note)
+		return ChatWithOpenAI(ctx, &cfg.OpenAI, messages)
 	default:
 		return "", fmt.Errorf("unsupported backend: %s", cfg.LLM.Backend)
 	}
 }
 
-func RewordNoteWithOpenAI(cfg *config.OpenAIConfig, note string) (string, error) {
+func systemPrompt(cfg *config.Config) string {
+	switch cfg.LLM.Backend {
+	case "openai":
+		return cfg.OpenAI.SystemPrompt
+	default:
+		return cfg.Ollama.SystemPrompt
+	}
+}
+
+func ChatWithOpenAI(ctx context.Context, cfg *config.OpenAIConfig, messages []ChatMessage) (string, error) {
 	payload := map[string]interface{}{
-		"model":  cfg.Model,
-		"stream": false,
-		"messages": []map[string]string{
-			{"role": "system", "content": cfg.SystemPrompt},
-			{"role": "user", "content": fmt.Sprintf(`Original note: %q`, note)},
-		},
+		"model":    cfg.Model,
+		"stream":   false,
+		"messages": messages,
 	}
 
 	body, err := json.Marshal(payload)
@@ -36,7 +105,7 @@ func RewordNoteWithOpenAI(cfg *config.OpenAIConfig, note string) (string, error)
 		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", openAIURL(cfg, "chat/completions"), bytes.NewReader(body))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -71,21 +140,22 @@ func RewordNoteWithOpenAI(cfg *config.OpenAIConfig, note string) (string, error)
 	return res.Choices[0].Message.Content, nil
 }
 
+// openAIURL joins an API path onto the configured base URL, so any
+// OpenAI-compatible server can be used.
+func openAIURL(cfg *config.OpenAIConfig, path string) string {
+	base := cfg.BaseURL
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	return strings.TrimRight(base, "/") + "/" + path
+}
+
 // This does the actual Ollama call.
-func RewordNoteWithOllama(cfg *config.OllamaConfig, note string) (string, error) {
+func ChatWithOllama(ctx context.Context, cfg *config.OllamaConfig, messages []ChatMessage) (string, error) {
 	payload := map[string]interface{}{
-		"model":  cfg.Model,
-		"stream": false,
-		"messages": []map[string]string{
-			{
-				"role":    "system",
-				"content": cfg.SystemPrompt,
-			},
-			{
-				"role":    "user",
-				"content": fmt.Sprintf("Reword this note: \"%s\"", note),
-			},
-		},
+		"model":    cfg.Model,
+		"stream":   false,
+		"messages": messages,
 	}
 
 	bodyBytes, err := json.Marshal(payload)
@@ -93,7 +163,12 @@ func RewordNoteWithOllama(cfg *config.OllamaConfig, note string) (string, error)
 		return "", fmt.Errorf("could not marshal payload: %w", err)
 	}
 
-	resp, err := http.Post(cfg.Endpoint, "application/json", bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.Endpoint, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("HTTP request failed: %w", err)
 	}
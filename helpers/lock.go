@@ -0,0 +1,172 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	lockWait  = 5 * time.Second  // how long to wait for another writer
+	staleLock = 30 * time.Second // a lock this old was left by a crash
+)
+
+// ErrChanged means a file changed since it was read and the edit couldn't
+// be carried over to the new version.
+var ErrChanged = errors.New("changed since it was read")
+
+// LockFile takes an advisory lock on path, so two writemes (say, a note
+// and the post-commit hook) don't write it at the same time. The lock is a
+// hidden file next to path; it only keeps out writers that ask for it.
+// Hold it from reading the file to writing it, and call unlock after.
+func LockFile(path string) (unlock func(), err error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	lock := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("could not lock %s: %w", path, err)
+		}
+
+		if isStale(lock) && breakLock(lock) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is being written by another writeme; if none is running, remove %s", filepath.Base(path), lock)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// breakLock removes a stale lock. Two writemes can find the same stale
+// lock, and the slower one must not remove the lock the faster one just
+// took, so only whoever creates the guard file may remove it, and only if
+// it's still stale then.
+func breakLock(lock string) bool {
+	guard := lock + ".stale"
+	g, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if isStale(guard) {
+			// Left by a crash between here and the Remove below
+			os.Remove(guard)
+		}
+		return false
+	}
+	g.Close()
+	defer os.Remove(guard)
+	if !isStale(lock) {
+		return false
+	}
+	return os.Remove(lock) == nil
+}
+
+func isStale(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > staleLock
+}
+
+// UpdateFile is how writeme writes back a file it read earlier: it takes
+// the lock, reads the file again and hands it to edit along with whether it
+// still matches read. edit returns the new content; if the file changed it
+// should redo its edit on the current version, or return ErrChanged to
+// abort. A missing file reads as empty. The write is journaled as action,
+// and an error wrapping ErrJournal means only that failed.
+func UpdateFile(path, read, action string, edit func(current string, changed bool) (string, error)) error {
+	return UpdateFiles([]string{path}, []string{read}, action, func(current []string, changed []bool) ([]string, error) {
+		updated, err := edit(current[0], changed[0])
+		return []string{updated}, err
+	})
+}
+
+// UpdateFiles is UpdateFile for several files written as one change, see
+// WriteFilesAtomic. Each file still gets its own undo step.
+func UpdateFiles(paths, reads []string, action string, edit func(current []string, changed []bool) ([]string, error)) error {
+	// Lock in path order, so two writemes can't each hold one lock while
+	// waiting for the other
+	order := make([]int, len(paths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return paths[order[a]] < paths[order[b]] })
+	for _, i := range order {
+		unlock, err := LockFile(paths[i])
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	before := make([][]byte, len(paths))
+	current := make([]string, len(paths))
+	changed := make([]bool, len(paths))
+	var names []string
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not read %s: %w", path, err)
+		}
+		if err == nil && data == nil {
+			// nil is for a missing file, see RecordEdit
+			data = []byte{}
+		}
+		before[i] = data
+		current[i] = string(data)
+		if changed[i] = !SameContent(data, reads[i]); changed[i] {
+			names = append(names, filepath.Base(path))
+		}
+	}
+
+	updated, err := edit(current, changed)
+	if err == ErrChanged {
+		return fmt.Errorf("%s %w; nothing was written, run it again", strings.Join(names, " and "), ErrChanged)
+	}
+	if err != nil {
+		return err
+	}
+
+	var writes []FileWrite
+	var wrote []int
+	for i, path := range paths {
+		if updated[i] != current[i] {
+			writes = append(writes, FileWrite{Path: path, Data: []byte(updated[i]), Perm: 0644})
+			wrote = append(wrote, i)
+		}
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	if err := WriteFilesAtomic(writes); err != nil {
+		return err
+	}
+	var journalErr error
+	for k, i := range wrote {
+		if err := RecordEdit(paths[i], action, before[i], writes[k].Data); err != nil && journalErr == nil {
+			journalErr = err
+		}
+	}
+	return journalErr
+}
+
+// Unchanged is an edit for UpdateFile that writes data as it is, or aborts
+// if the file changed since it was read.
+func Unchanged(data string) func(string, bool) (string, error) {
+	return func(current string, changed bool) (string, error) {
+		if changed {
+			return "", ErrChanged
+		}
+		return data, nil
+	}
+}
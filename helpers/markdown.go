@@ -0,0 +1,55 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	headingStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("5"))
+	bulletStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	codeStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	boldStyle    = lipgloss.NewStyle().Bold(true)
+	fenceStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+
+	codeSpan  = regexp.MustCompile("`[^`]+`")
+	boldSpan  = regexp.MustCompile(`\*\*[^*]+\*\*`)
+	bulletPre = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])(\s)`)
+)
+
+// HighlightMarkdown colours Markdown lines for the terminal: headings, list
+// markers, code spans, bold text and fenced code blocks.
+func HighlightMarkdown(lines []string) []string {
+	out := make([]string, len(lines))
+	inFence := false
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inFence = !inFence
+			out[i] = fenceStyle.Render(line)
+		case inFence:
+			out[i] = codeStyle.Render(line)
+		default:
+			if _, _, ok := parseHeading(line); ok {
+				out[i] = headingStyle.Render(line)
+				continue
+			}
+			out[i] = highlightInline(line)
+		}
+	}
+	return out
+}
+
+func highlightInline(line string) string {
+	if m := bulletPre.FindStringSubmatchIndex(line); m != nil {
+		marker := line[m[4]:m[5]]
+		line = line[:m[4]] + bulletStyle.Render(marker) + line[m[5]:]
+	}
+	line = codeSpan.ReplaceAllStringFunc(line, func(s string) string { return codeStyle.Render(s) })
+	line = boldSpan.ReplaceAllStringFunc(line, func(s string) string { return boldStyle.Render(s) })
+	return line
+}
@@ -0,0 +1,259 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"writeme/config"
+)
+
+// Output contracts a mode can promise. They decide how the model's reply is
+// turned into bullets.
+const (
+	OutputLine    = "line"    // a single line, inserted as one bullet
+	OutputBullets = "bullets" // a list, inserted as one bullet per item
+	OutputTask    = "task"    // a single line, inserted as a "[ ] " task
+)
+
+// multilineHint is added to the prompt for notes of more than one line.
+const multilineHint = "The note has several lines. Keep the line breaks and return all of its lines, nothing else."
+
+// DefaultMode is what -a uses when --mode isn't given.
+const DefaultMode = "reword"
+
+// Mode is a named rewrite: its own prompts plus an output contract.
+type Mode struct {
+	Name         string
+	Description  string
+	SystemPrompt string // empty means the backend's system_prompt
+	UserPrompt   string // empty means llm.user_prompt
+	Output       string
+}
+
+const modeUserPrompt = `Section: {{.Path}}
+Note: "{{.Note}}"`
+
+var builtinModes = map[string]Mode{
+	"reword": {
+		Description: "reword the note per your system prompt",
+		Output:      OutputLine,
+	},
+	"summarize": {
+		Description: "shorten the note to its essentials",
+		SystemPrompt: `You summarize notes for developer documentation.
+- Keep only the essential information.
+- Never add new information.
+- Return exactly one short line, no greeting or preamble.`,
+		Output: OutputLine,
+	},
+	"expand": {
+		Description: "spell out a terse note as a full sentence",
+		SystemPrompt: `You expand terse notes for developer documentation.
+- Turn shorthand and fragments into complete, clear sentences.
+- Do not invent facts that aren't implied by the note.
+- Return exactly one line, no greeting or preamble.`,
+		Output: OutputLine,
+	},
+	"grammar": {
+		Description: "fix spelling and grammar only",
+		SystemPrompt: `You fix spelling, grammar and punctuation.
+- Change nothing else: keep the wording, tone and meaning.
+- Return exactly one line with the corrected note and nothing else.`,
+		Output: OutputLine,
+	},
+	"translate": {
+		Description: "translate the note to English",
+		SystemPrompt: `You translate notes into English for developer documentation.
+- Keep code, identifiers and proper nouns as they are.
+- If the note is already English, return it unchanged.
+- Return exactly one line with the translation and nothing else.`,
+		Output: OutputLine,
+	},
+	"task": {
+		Description: "turn the note into an actionable task",
+		SystemPrompt: `You turn notes into actionable tasks.
+- Start with an imperative verb.
+- Keep it to one short line.
+- Return only the task text, without checkboxes or bullets.`,
+		Output: OutputTask,
+	},
+	"bulletize": {
+		Description: "split the note into several bullets",
+		SystemPrompt: `You split notes into separate points for developer documentation.
+- Output one point per line, each starting with "- ".
+- Keep the meaning; don't add information.
+- Output only the list.`,
+		Output: OutputBullets,
+	},
+}
+
+// ResolveMode finds a mode by name. Modes from the config win over built-ins
+// of the same name.
+func ResolveMode(cfg *config.Config, name string) (Mode, error) {
+	if name == "" {
+		name = DefaultMode
+	}
+
+	mode, ok := builtinModes[name]
+	if custom, found := cfg.Modes[name]; found {
+		mode = Mode{
+			Description:  custom.Description,
+			SystemPrompt: custom.SystemPrompt,
+			UserPrompt:   custom.UserPrompt,
+			Output:       custom.Output,
+		}
+		ok = true
+	}
+	if !ok {
+		return Mode{}, fmt.Errorf("unknown mode %q (available: %s)", name, strings.Join(ModeNames(cfg), ", "))
+	}
+
+	mode.Name = name
+	if mode.Output == "" {
+		mode.Output = OutputLine
+	}
+	switch mode.Output {
+	case OutputLine, OutputBullets, OutputTask:
+	default:
+		return Mode{}, fmt.Errorf("mode %q has unknown output %q (want line, bullets or task)", name, mode.Output)
+	}
+	return mode, nil
+}
+
+// ModeNames lists built-in and configured mode names, sorted.
+func ModeNames(cfg *config.Config) []string {
+	seen := map[string]bool{}
+	var names []string
+	for name := range builtinModes {
+		seen[name] = true
+		names = append(names, name)
+	}
+	if cfg != nil {
+		for name := range cfg.Modes {
+			if !seen[name] {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseModeOutput turns a model reply into notes according to the output
+// contract.
+func ParseModeOutput(output, reply string) ([]string, error) {
+	switch output {
+	case OutputBullets:
+		notes := ParseBullets(reply)
+		if len(notes) == 0 {
+			return nil, fmt.Errorf("model returned no bullets")
+		}
+		return notes, nil
+	case OutputTask:
+		line := firstLine(reply)
+		line = taskPrefix.ReplaceAllString(line, "")
+		if line == "" {
+			return nil, fmt.Errorf("model returned an empty task")
+		}
+		return []string{"[ ] " + line}, nil
+	default:
+		line := firstLine(reply)
+		if line == "" {
+			return nil, fmt.Errorf("model returned an empty note")
+		}
+		return []string{line}, nil
+	}
+}
+
+// parseMultiline keeps a whole reply as one note, for notes that span lines.
+// Preambles are dropped like for a single line.
+func parseMultiline(reply string) ([]string, error) {
+	lines := replyLines(reply)
+	lines[0] = answerLabel.ReplaceAllString(strings.TrimSpace(lines[0]), "")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	note := cleanLine(strings.Join(lines, "\n"))
+	if note == "" {
+		return nil, fmt.Errorf("model returned an empty note")
+	}
+	return []string{note}, nil
+}
+
+var (
+	listMarker = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+	taskPrefix = regexp.MustCompile(`^(?i)(?:\[[ x]\]\s*|todo:?\s*)+`)
+	// preamble matches the chatter models put before the answer, like
+	// "Sure! Here is the reworded note:".
+	preamble = regexp.MustCompile(`^(?i)(?:(?:sure|certainly|of course|absolutely|okay|ok|alright|great)\b|here(?:'s| is| are)\b|(?:the )?(?:reworded|rewritten|revised|corrected|translated|summarized|expanded) (?:note|version|text|task)\b)`)
+	// answerLabel is a preamble on the same line as the answer, like
+	// "Reworded note: ...".
+	answerLabel = regexp.MustCompile(`^(?i)(?:the )?(?:reworded|rewritten|revised|corrected|translated|summarized|expanded) (?:note|version|text|task):\s*`)
+)
+
+// ParseBullets splits a Markdown-ish list into items. Lines without a list
+// marker are folded into the item before them, and any preamble before the
+// first marker ("Here are the points:") is dropped.
+func ParseBullets(text string) []string {
+	lines := strings.Split(text, "\n")
+	hasMarkers := false
+	for _, line := range lines {
+		if listMarker.MatchString(line) {
+			hasMarkers = true
+			break
+		}
+	}
+
+	var notes []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if len(notes) == 0 && hasMarkers && !listMarker.MatchString(line) {
+			continue
+		}
+		if listMarker.MatchString(line) || len(notes) == 0 {
+			notes = append(notes, cleanLine(listMarker.ReplaceAllString(line, "")))
+			continue
+		}
+		notes[len(notes)-1] += " " + trimmed
+	}
+	return notes
+}
+
+// firstLine returns the first non-empty line of a reply after any preamble,
+// without list markers or wrapping quotes.
+func firstLine(reply string) string {
+	for _, line := range replyLines(reply) {
+		line = answerLabel.ReplaceAllString(strings.TrimSpace(line), "")
+		if line = cleanLine(listMarker.ReplaceAllString(line, "")); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// replyLines splits a reply into lines, dropping leading blank lines and
+// preamble lines. A preamble is only dropped when something follows it, so
+// a one-line answer that happens to start with "Sure" is kept.
+func replyLines(reply string) []string {
+	lines := strings.Split(strings.TrimSpace(reply), "\n")
+	for len(lines) > 1 {
+		line := strings.TrimSpace(lines[0])
+		if line != "" && !preamble.MatchString(line) && !strings.HasSuffix(line, ":") {
+			break
+		}
+		lines = lines[1:]
+	}
+	return lines
+}
+
+func cleanLine(line string) string {
+	line = strings.TrimSpace(line)
+	if len(line) >= 2 && line[0] == '"' && line[len(line)-1] == '"' {
+		line = strings.TrimSpace(line[1 : len(line)-1])
+	}
+	return line
+}
@@ -1,19 +1,42 @@
 package helpers
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/manifoldco/promptui"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
-// EnsureTopLevelHeading checks for a top-level heading and adds it if missing.
-func EnsureTopLevelHeading(content string) (string, error) {
-	if strings.Contains(content, "# ") {
-		// Already has a top-level heading
+// EnsureTopLevelHeading checks for a top-level heading and adds it if missing,
+// writing the file back to path.
+func EnsureTopLevelHeading(path, content string) (string, error) {
+	if len(HeadingLines(strings.Split(content, "\n"))) > 0 {
+		// Already has a heading
+		return content, nil
+	}
+
+	// Write it back immediately
+	err := UpdateFile(path, content, "add heading", func(current string, _ bool) (string, error) {
+		var err error
+		content, err = AddTopLevelHeading(current)
+		return content, err
+	})
+	if err != nil && !errors.Is(err, ErrJournal) {
+		return "", err
+	}
+	return content, nil
+}
+
+// AddTopLevelHeading adds "# {dirname}" to content if it has no heading yet.
+// It goes below any logo or badges at the top.
+func AddTopLevelHeading(content string) (string, error) {
+	lines := strings.Split(content, "\n")
+	if len(HeadingLines(lines)) > 0 {
 		return content, nil
 	}
 
@@ -24,16 +47,22 @@ func EnsureTopLevelHeading(content string) (string, error) {
 	}
 	dirName := filepath.Base(wd)
 
-	// Add # {dirname} at the top
-	newContent := fmt.Sprintf("# %s\n%s", dirName, content)
-
-	// Write it back immediately
-	err = os.WriteFile("NOTES.md", []byte(newContent), 0644)
-	if err != nil {
-		return "", fmt.Errorf("could not write NOTES.md: %w", err)
+	// Add # {dirname} after the leading HTML/badge block, if there is one
+	at := 0
+	for at < len(lines) && strings.TrimSpace(lines[at]) != "" && isPreamble(lines[at]) {
+		at++
 	}
+	heading := []string{"# " + dirName}
+	if at > 0 {
+		heading = []string{"", "# " + dirName}
+	}
+	return strings.Join(splice(lines, at, at, heading), "\n"), nil
+}
 
-	return newContent, nil
+// isPreamble is true for the HTML and badge lines READMEs often open with.
+func isPreamble(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "<") || strings.HasPrefix(line, "[![") || strings.HasPrefix(line, "![")
 }
 
 type HeadingNode struct {
@@ -50,93 +79,259 @@ func ParseHeadings(content string) *HeadingNode {
 
 	stack := []*HeadingNode{root}
 
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "#") {
-			continue
+	lines := strings.Split(content, "\n")
+	walkHeadings(lines, len(lines), func(_, depth, level int, title string) {
+		node := &HeadingNode{
+			Level: level,
+			Title: title,
 		}
 
-		level := 0
-		for level < len(line) && line[level] == '#' {
-			level++
-		}
+		// stack[depth] is the parent, whatever level it is
+		parent := stack[depth]
+		parent.Children = append(parent.Children, node)
 
-		// Only consider headings like "# title"
-		if len(line) <= level || line[level] != ' ' {
-			continue
-		}
+		stack = append(stack[:depth+1], node)
+	})
 
-		title := strings.TrimSpace(line[level+1:])
+	return root
+}
 
-		node := &HeadingNode{
-			Level: level,
-			Title: title,
-		}
+// recentPickerItems is how many recent sections are offered at the top of
+// the picker.
+const recentPickerItems = 3
 
-		// Pop stack until we find the correct parent
-		for len(stack) > 0 && stack[len(stack)-1].Level >= level {
-			stack = stack[:len(stack)-1]
-		}
+// headingItem is a row in the section picker.
+type headingItem struct {
+	node   *HeadingNode
+	here   bool     // "insert at this level" rather than a child heading
+	recent []string // a recently used section, chosen in one step
+}
 
-		parent := stack[len(stack)-1]
-		parent.Children = append(parent.Children, node)
+func (i headingItem) Title() string {
+	if i.recent != nil {
+		return "★ " + strings.Join(i.recent, PathSeparator)
+	}
+	if i.here {
+		return "⏎ Insert here"
+	}
+	return i.node.Title
+}
 
-		stack = append(stack, node)
+func (i headingItem) Description() string {
+	if i.recent != nil {
+		return "recently used"
 	}
+	if i.here {
+		return "add the note directly under this heading"
+	}
+	switch n := len(i.node.Children); n {
+	case 0:
+		return "no subsections"
+	case 1:
+		return "1 subsection"
+	default:
+		return fmt.Sprintf("%d subsections", n)
+	}
+}
 
-	return root
+func (i headingItem) FilterValue() string {
+	if i.recent != nil {
+		return strings.Join(i.recent, PathSeparator)
+	}
+	if i.here {
+		return ""
+	}
+	return i.node.Title
 }
 
-// SelectPlacement walks the tree and returns the selected path.
-func SelectPlacement(node *HeadingNode) ([]string, error) {
-	var path []string
-	current := node
+// PickerModel walks the heading tree one level at a time. Type to filter a
+// level, Enter drills into a section, and Backspace/← goes back up. Tab
+// switches to searching every section at once.
+type PickerModel struct {
+	root     *HeadingNode
+	stack    []*HeadingNode // current level is stack[len(stack)-1]
+	list     list.Model
+	all      list.Model // flattened "search all sections" list
+	search   bool
+	recent   [][]string
+	selected []string
+	done     bool
+	name     string // file name shown in the title
+}
 
-	for {
-		if len(current.Children) == 0 {
-			if current.Title != "ROOT" {
-				path = append(path, current.Title)
-			}
-			return path, nil
+// NewPickerModel builds a picker over the tree. recent sections, most recent
+// first, are ranked higher when searching.
+func NewPickerModel(root *HeadingNode, recent [][]string) PickerModel {
+	delegate := list.NewDefaultDelegate()
+	l := list.New(nil, delegate, 0, 0)
+	l.SetShowStatusBar(false)
+	l.DisableQuitKeybindings()
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open/choose")),
+			key.NewBinding(key.WithKeys("backspace", "left"), key.WithHelp("←", "back")),
+			key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "search all")),
 		}
+	}
 
-		var options []string
-		for _, child := range current.Children {
-			options = append(options, child.Title)
-		}
+	m := PickerModel{
+		root:   root,
+		stack:  []*HeadingNode{root},
+		list:   l,
+		all:    newSectionSearchList(root, recent),
+		recent: recent,
+		name:   "NOTES.md",
+	}
+	m.refresh()
+	return m
+}
 
-		// Only offer INSERT AT THIS LEVEL if not ROOT
-		if len(options) > 0 && current.Title != "ROOT" {
-			options = append(options, "INSERT AT THIS LEVEL")
-		}
+// WithName shows name instead of NOTES.md in the picker's title.
+func (m PickerModel) WithName(name string) PickerModel {
+	m.name = name
+	m.refresh()
+	return m
+}
 
-		prompt := promptui.Select{
-			Label: fmt.Sprintf("Choose section under '%s'", current.Title),
-			Items: options,
-		}
+// Path returns the heading titles of the current level, root excluded.
+func (m PickerModel) Path() []string {
+	var path []string
+	for _, node := range m.stack[1:] {
+		path = append(path, node.Title)
+	}
+	return path
+}
 
-		_, result, err := prompt.Run()
-		if err != nil {
-			return nil, fmt.Errorf("prompt failed: %w", err)
-		}
+// refresh lists the current level's children.
+func (m *PickerModel) refresh() {
+	current := m.stack[len(m.stack)-1]
 
-		if result == "INSERT AT THIS LEVEL" {
-			if current.Title != "ROOT" {
-				path = append(path, current.Title)
+	var items []list.Item
+	if current == m.root {
+		for i, path := range m.recent {
+			if i == recentPickerItems {
+				break
 			}
-			return path, nil
+			items = append(items, headingItem{recent: path})
 		}
+	} else {
+		items = append(items, headingItem{node: current, here: true})
+	}
+	for _, child := range current.Children {
+		items = append(items, headingItem{node: child})
+	}
+
+	m.list.ResetFilter()
+	m.list.SetItems(items)
+	m.list.Select(0)
+
+	crumbs := append([]string{m.name}, m.Path()...)
+	m.list.Title = strings.Join(crumbs, " › ")
+}
+
+func (m PickerModel) Init() tea.Cmd {
+	return nil
+}
 
-		// Drill down
-		for _, child := range current.Children {
-			if child.Title == result {
-				if current.Title != "ROOT" {
-					path = append(path, current.Title)
-				}
-				current = child
+func (m PickerModel) Update(msg tea.Msg) (PickerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height)
+		m.all.SetSize(msg.Width, msg.Height)
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.search {
+			return m.updateSearch(msg)
+		}
+		if msg.String() == "tab" && m.list.FilterState() != list.Filtering {
+			return m.startSearch()
+		}
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch msg.String() {
+		case "enter":
+			item, ok := m.list.SelectedItem().(headingItem)
+			if !ok {
+				return m, nil
+			}
+			if item.recent != nil {
+				m.selected = item.recent
+				m.done = true
+				return m, nil
+			}
+			if item.here {
+				m.selected = m.Path()
+				m.done = true
+				return m, nil
+			}
+			m.stack = append(m.stack, item.node)
+			if len(item.node.Children) == 0 {
+				m.selected = m.Path()
+				m.done = true
+				return m, nil
+			}
+			m.refresh()
+			return m, nil
+		case "backspace", "left", "h":
+			if m.list.FilterState() == list.FilterApplied {
 				break
 			}
+			if len(m.stack) > 1 {
+				m.stack = m.stack[:len(m.stack)-1]
+				m.refresh()
+			}
+			return m, nil
 		}
 	}
+
+	var cmd tea.Cmd
+	if m.search {
+		m.all, cmd = m.all.Update(msg)
+		return m, cmd
+	}
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// startSearch switches to the flattened list with the filter box open.
+func (m PickerModel) startSearch() (PickerModel, tea.Cmd) {
+	m.search = true
+	m.all.ResetFilter()
+	m.all.Select(0)
+	var cmd tea.Cmd
+	m.all, cmd = m.all.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	return m, cmd
+}
+
+func (m PickerModel) updateSearch(msg tea.KeyMsg) (PickerModel, tea.Cmd) {
+	switch msg.String() {
+	case "tab", "esc":
+		m.search = false
+		return m, nil
+	case "enter":
+		if item, ok := m.all.SelectedItem().(sectionItem); ok {
+			m.selected = item.path
+			m.done = true
+		}
+		return m, nil
+	case "up", "ctrl+p":
+		m.all.CursorUp()
+		return m, nil
+	case "down", "ctrl+n":
+		m.all.CursorDown()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.all, cmd = m.all.Update(msg)
+	return m, cmd
+}
+
+func (m PickerModel) View() string {
+	if m.search {
+		return m.all.View()
+	}
+	return m.list.View()
 }
@@ -1,94 +1,411 @@
 package helpers
 
 import (
+	"fmt"
 	"strings"
 
-	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+var (
+	addedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	gutterStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	titleStyle  = lipgloss.NewStyle().Bold(true)
+	helpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// PreviewResult is what the user settled on in the preview.
+type PreviewResult struct {
+	Notes     []string
+	At        int      // index in the original lines the notes go before
+	Section   []string // heading path of the section at At
+	Confirmed bool
+}
+
+// PreviewModel shows the whole file as a diff with the new notes spliced in.
+// The notes are edited in a textarea under the diff, and can be moved to
+// another line or section before confirming.
 type PreviewModel struct {
-	linesAbove []string
-	linesBelow []string
-	input      textinput.Model
-	confirmed  bool
-	finalNote  string
-}
-
-func NewPreviewModel(linesAbove, linesBelow []string, initialNote string) PreviewModel {
-	ti := textinput.New()
-	ti.Placeholder = ""
-	ti.Prompt = "+ - " // show + and bullet
-	ti.CharLimit = 500
-	ti.SetValue(initialNote)
-	ti.Focus()
-	ti.CursorEnd()
+	name    string   // file name for the diff header
+	base    []string // file lines without the new notes
+	at      int      // the notes go before base[at]
+	editor  textarea.Model
+	diff    diffView
+	editing bool
+	width   int
+	height  int
+	result  PreviewResult
+
+	// Only set when the notes came from the AI
+	original []string
+	aiNotes  []string
+	reroll   func() ([]string, error)
+	compare  bool
+	rolling  bool
+	status   string
+
+	embedded bool // part of NoteFlowModel rather than its own program
+}
+
+// rerollMsg carries the result of asking the AI again.
+type rerollMsg struct {
+	notes []string
+	err   error
+}
+
+// WithComparison lets the user compare the AI notes with what they typed,
+// switch between the two, and ask the AI again with reroll (may be nil).
+func (m PreviewModel) WithComparison(original []string, reroll func() ([]string, error)) PreviewModel {
+	m.original = original
+	m.aiNotes = ParseNotes(m.editor.Value())
+	m.reroll = reroll
+	m.compare = true
+	return m
+}
 
+// setNotes replaces the editor content with notes.
+func (m *PreviewModel) setNotes(notes []string) {
+	m.editor.SetValue(strings.Join(FormatNotes(notes), "\n"))
+	m.layout()
+}
+
+func NewPreviewModel(name string, base []string, at int, initialNotes []string) PreviewModel {
 	return PreviewModel{
-		linesAbove: linesAbove,
-		linesBelow: linesBelow,
-		input:      ti,
+		name:    name,
+		base:    base,
+		at:      at,
+		editor:  NewNoteEditor(initialNotes),
+		editing: true,
 	}
 }
 
+// NewNoteEditor returns a focused textarea holding notes as Markdown
+// bullets, so the user can edit text, add continuation lines or start new
+// bullets. Read it back with ParseNotes.
+func NewNoteEditor(notes []string) textarea.Model {
+	text := strings.Join(FormatNotes(notes), "\n")
+
+	ta := textarea.New()
+	ta.Placeholder = "- your note"
+	ta.Prompt = "+ " // mark the added lines
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	ta.SetWidth(80)
+	ta.SetHeight(editorHeight(text))
+	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	ta.FocusedStyle.Text = addedStyle
+	ta.FocusedStyle.Prompt = addedStyle
+	ta.SetValue(text)
+	ta.Focus()
+	return ta
+}
+
+// editorHeight grows the textarea with its content, within reason.
+func editorHeight(text string) int {
+	h := strings.Count(text, "\n") + 2
+	if h < 3 {
+		h = 3
+	}
+	if h > 10 {
+		h = 10
+	}
+	return h
+}
+
 func (m PreviewModel) Init() tea.Cmd {
-	return textinput.Blink
+	return textarea.Blink
 }
 
 func (m PreviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.editor.SetWidth(msg.Width - 2)
+		m.diff.resize(msg.Width, 1)
+		m.layout()
+		m.jumpToChange()
+		return m, nil
+
+	case rerollMsg:
+		m.rolling = false
+		if msg.err != nil {
+			m.status = "AI failed: " + msg.err.Error()
+			return m, nil
+		}
+		m.aiNotes = msg.notes
+		m.setNotes(msg.notes)
+		m.status = "Got a new AI version."
+		return m, nil
+
 	case tea.KeyMsg:
+		m.status = ""
 		switch msg.String() {
-		case "enter":
-			m.finalNote = m.input.Value()
-			m.confirmed = true
-			return m, tea.Quit
-		case "esc", "q":
-			m.confirmed = false
-			return m, tea.Quit
+		case "ctrl+s":
+			return m.confirm()
+		case "esc", "ctrl+c":
+			m.result.Confirmed = false
+			return m, m.finish()
+		case "tab":
+			m.editing = !m.editing
+			if m.editing {
+				return m, m.editor.Focus()
+			}
+			m.editor.Blur()
+			return m, nil
+		case "alt+up":
+			return m.moveTo(m.at - 1), nil
+		case "alt+down":
+			return m.moveTo(m.at + 1), nil
+		case "pgup", "pgdown":
+			m.diff, cmd = m.diff.update(msg)
+			return m, cmd
+		}
+
+		if !m.editing {
+			switch diffKey(msg.String()) {
+			case diffConfirm:
+				return m.confirm()
+			case diffCancel:
+				m.result.Confirmed = false
+				return m, m.finish()
+			}
+			switch msg.String() {
+			case "e":
+				m.editing = true
+				return m, m.editor.Focus()
+			case "g":
+				m.jumpToChange()
+				return m, nil
+			case "K", "shift+up":
+				return m.moveTo(m.at - 1), nil
+			case "J", "shift+down":
+				return m.moveTo(m.at + 1), nil
+			case "[":
+				return m.moveSection(-1), nil
+			case "]":
+				return m.moveSection(1), nil
+			}
+			if m.original != nil {
+				switch msg.String() {
+				case "c":
+					m.compare = !m.compare
+					m.layout()
+					return m, nil
+				case "a":
+					m.setNotes(m.aiNotes)
+					m.status = "Using the AI version."
+					return m, nil
+				case "o":
+					m.setNotes(m.original)
+					m.status = "Reverted to your original note."
+					return m, nil
+				case "r":
+					if m.reroll == nil || m.rolling {
+						return m, nil
+					}
+					m.rolling = true
+					m.status = "Asking the AI again..."
+					reroll := m.reroll
+					return m, func() tea.Msg {
+						notes, err := reroll()
+						return rerollMsg{notes, err}
+					}
+				}
+			}
+			m.diff, cmd = m.diff.update(msg)
+			return m, cmd
 		}
 	}
 
-	m.input, cmd = m.input.Update(msg)
+	if m.editing {
+		m.editor, cmd = m.editor.Update(msg)
+		m.layout()
+		return m, cmd
+	}
+
+	m.diff, cmd = m.diff.update(msg)
 	return m, cmd
 }
 
-func (m PreviewModel) View() string {
-	var b strings.Builder
+func (m PreviewModel) confirm() (tea.Model, tea.Cmd) {
+	m.result = PreviewResult{
+		Notes:   ParseNotes(m.editor.Value()),
+		At:      m.at,
+		Section: HeadingPathAt(m.base, m.at-1),
+	}
+	m.result.Confirmed = len(m.result.Notes) > 0
+	return m, m.finish()
+}
+
+// previewDoneMsg tells a parent model the embedded preview is finished.
+type previewDoneMsg struct {
+	result PreviewResult
+}
+
+// finish quits the program, or hands the result to the parent model when
+// the preview is embedded in a larger flow.
+func (m PreviewModel) finish() tea.Cmd {
+	if !m.embedded {
+		return tea.Quit
+	}
+	result := m.result
+	return func() tea.Msg { return previewDoneMsg{result} }
+}
+
+// layout sizes the editor and viewport to the window and re-renders the diff.
+func (m *PreviewModel) layout() {
+	if !m.diff.ready {
+		return
+	}
+	m.editor.SetHeight(editorHeight(m.editor.Value()))
+
+	// title, section line, blank, editor, blank, help
+	h := m.height - m.editor.Height() - 5
+	if m.original != nil {
+		h-- // second help line
+	}
+	if m.showCompare() {
+		h -= lipgloss.Height(m.comparison()) + 1
+	}
+	m.diff.resize(m.width, h)
+	m.diff.setContent(m.diffLines(), []int{m.at + 3}) // +3 for the diff header lines
+}
+
+// moveTo puts the notes before base[at], keeping them below the first
+// heading.
+func (m PreviewModel) moveTo(at int) PreviewModel {
+	minAt := 0
+	if heads := HeadingLines(m.base); len(heads) > 0 {
+		minAt = heads[0] + 1
+	}
+	if at < minAt {
+		at = minAt
+	}
+	if at > len(m.base) {
+		at = len(m.base)
+	}
+	m.at = at
+	m.layout()
+	m.jumpToChange()
+	return m
+}
+
+// moveSection moves the notes to the end of the previous (dir < 0) or next
+// section's bullets.
+func (m PreviewModel) moveSection(dir int) PreviewModel {
+	heads := HeadingLines(m.base)
+	current := -1
+	for i, h := range heads {
+		if h < m.at {
+			current = i
+		}
+	}
+
+	target := current + dir
+	if target < 0 || target >= len(heads) {
+		return m
+	}
+	return m.moveTo(sectionInsertionPoint(m.base, heads[target]))
+}
+
+func (m PreviewModel) showCompare() bool {
+	return m.original != nil && m.compare
+}
+
+func (m PreviewModel) comparison() string {
+	return RenderComparison(m.original, m.aiNotes, m.width)
+}
+
+// jumpToChange scrolls so the inserted lines sit in the upper third.
+func (m *PreviewModel) jumpToChange() {
+	if !m.diff.ready {
+		return
+	}
+	m.diff.scrollTo(m.at+3, true) // +3 for the diff header lines
+}
+
+// diffLines renders the whole file as a unified diff against base.
+func (m PreviewModel) diffLines() []string {
+	notes := ParseNotes(m.editor.Value())
+	lines, added := m.base, 0
+	if len(notes) > 0 {
+		lines, _ = SpliceNotes(m.base, m.at, notes)
+		added = len(lines) - len(m.base)
+	}
+
+	highlighted := HighlightMarkdown(lines)
+	width := len(fmt.Sprint(len(lines)))
 
-	title := lipgloss.NewStyle().Bold(true).Render("--- Proposed Change Preview ---")
-	b.WriteString("\n" + title + "\n\n")
+	out := []string{
+		gutterStyle.Render("--- a/" + m.name),
+		gutterStyle.Render("+++ b/" + m.name),
+		gutterStyle.Render(fmt.Sprintf("@@ -1,%d +1,%d @@", len(m.base), len(lines))),
+	}
+	for i, line := range lines {
+		num := gutterStyle.Render(fmt.Sprintf("%*d ", width, i+1))
+		if i >= m.at && i < m.at+added {
+			out = append(out, num+addedStyle.Render("+ "+line))
+			continue
+		}
+		out = append(out, num+"  "+highlighted[i])
+	}
+	return out
+}
 
-	for _, line := range m.linesAbove {
-		b.WriteString("  " + line + "\n")
+func (m PreviewModel) View() string {
+	if !m.diff.ready {
+		return "\n  Loading preview..."
 	}
-	greenInput := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Render(m.input.View())
-	b.WriteString(greenInput + "\n")
 
-	// b.WriteString(m.input.View() + "\n")
+	var b strings.Builder
 
-	for _, line := range m.linesBelow {
-		b.WriteString("  " + line + "\n")
+	b.WriteString(m.diff.view("Adding to: "+strings.Join(HeadingPathAt(m.base, m.at-1), " > ")) + "\n")
+	if m.showCompare() {
+		b.WriteString(m.comparison() + "\n")
 	}
+	b.WriteString(m.editor.View() + "\n\n")
 
-	b.WriteString("\n[Enter = confirm, Esc = cancel]\n")
+	if m.status != "" {
+		b.WriteString(m.status + "  ")
+	}
+	if m.editing {
+		b.WriteString(helpStyle.Render("Ctrl+S confirm · Tab browse file · Alt+↑/↓ move · Esc cancel · start a line with \"- \" for a new bullet"))
+	} else {
+		b.WriteString(helpStyle.Render("Enter confirm · Tab/e edit · ↑/↓ scroll · g jump to change · J/K move line · [/] move section · q cancel"))
+		if m.original != nil {
+			b.WriteString("\n" + helpStyle.Render("c compare · a use AI text · o use original · r ask AI again"))
+		}
+	}
 	return b.String()
 }
 
-func RunPreviewWithEdit(linesAbove, linesBelow []string, initialNote string) (string, bool, error) {
-	m := NewPreviewModel(linesAbove, linesBelow, initialNote)
+// RunPreviewWithEdit shows the notes spliced into the file before base[at]
+// and lets the user edit and move them. Bullets cleared by the user are
+// dropped from the result.
+func RunPreviewWithEdit(name string, base []string, at int, initialNotes []string) (PreviewResult, error) {
+	return RunPreview(NewPreviewModel(name, base, at, initialNotes))
+}
+
+// RunPreview runs a preview model built with NewPreviewModel.
+func RunPreview(m PreviewModel) (PreviewResult, error) {
+	tty, done, err := TerminalInput()
+	if err != nil {
+		return PreviewResult{}, err
+	}
+	defer done()
 
-	p := tea.NewProgram(m)
+	p := tea.NewProgram(m, tea.WithInput(tty), tea.WithAltScreen())
 	finalModel, err := p.Run()
 	if err != nil {
-		return "", false, err
+		return PreviewResult{}, err
 	}
 
-	mFinal := finalModel.(PreviewModel)
-	return mFinal.finalNote, mFinal.confirmed, nil
+	return finalModel.(PreviewModel).result, nil
 }
 
 // package helpers
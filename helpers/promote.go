@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"writeme/config"
+)
+
+const polishSystemPrompt = `You turn a developer's rough notes into polished documentation.
+Rules:
+- Keep the meaning exactly the same. Do not add new information.
+- Write clear, direct prose in the voice of a README.
+- No preamble, no closing remarks, no headings.`
+
+// PromotedMark is appended to notes that were promoted with --mark.
+func PromotedMark(target string) string {
+	return fmt.Sprintf(" _(promoted to %s)_", target)
+}
+
+// PromoteCandidates lists the bullets that can be promoted: those in the
+// sections matching in and containing query (both optional), minus notes
+// already marked as promoted.
+func PromoteCandidates(file, content string, in []string, query string) []SearchHit {
+	var out []SearchHit
+	for _, n := range AllNotes(file, content, in) {
+		if strings.Contains(n.Text, " _(promoted to ") {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(n.Text), strings.ToLower(query)) {
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// LocateNotes finds notes picked from before, an earlier version of a file,
+// again in content by their section and text, and returns the lines they
+// start on now (1-based). A note that's there more than once is told apart
+// by which occurrence it was. ok is false if any of them was edited, moved
+// to another section or removed, or its duplicates changed.
+func LocateNotes(file, before, content string, notes []SearchHit) (lines []int, ok bool) {
+	key := func(n SearchHit) string { return strings.Join(n.Section, "\x00") + "\x00\x00" + n.Text }
+	occurrences := func(content string) map[string][]int {
+		byKey := map[string][]int{}
+		for _, n := range AllNotes(file, content, nil) {
+			byKey[key(n)] = append(byKey[key(n)], n.Line)
+		}
+		return byKey
+	}
+	then, now := occurrences(before), occurrences(content)
+
+	for _, n := range notes {
+		k := key(n)
+		if len(then[k]) != len(now[k]) {
+			return nil, false
+		}
+		found := false
+		for i, line := range then[k] {
+			if line == n.Line {
+				lines = append(lines, now[k][i])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return lines, true
+}
+
+// RemoveNotes deletes the bullets starting on the given lines (1-based, as
+// in SearchHit), continuation lines included. With a non-empty mark the
+// bullets are kept and mark is appended to their first line instead.
+func RemoveNotes(lines []string, starts []int, mark string) []string {
+	want := map[int]bool{}
+	for _, s := range starts {
+		want[s-1] = true
+	}
+
+	var items []SectionItem
+	for _, h := range HeadingLines(lines) {
+		for _, item := range SectionItems(lines, h) {
+			if want[item.Start] {
+				items = append(items, item)
+			}
+		}
+	}
+	// Bottom up, so earlier line numbers stay valid
+	sort.Slice(items, func(i, j int) bool { return items[i].Start > items[j].Start })
+
+	out := append([]string{}, lines...)
+	for _, item := range items {
+		if mark != "" {
+			out[item.Start] += mark
+			continue
+		}
+		out = splice(out, item.Start, item.End, nil)
+	}
+	return out
+}
+
+// PolishNotes asks the configured backend to turn notes into README-ready
+// text for the section of lines at line at: one paragraph in a section
+// written as paragraphs, tidied-up bullets otherwise.
+func PolishNotes(cfg *config.Config, lines []string, at int, notes []string) ([]string, error) {
+	section := strings.Join(HeadingPathAt(lines, at-1), PathSeparator)
+
+	var b strings.Builder
+	output := OutputBullets
+	if paragraphSection(lines, at) {
+		output = OutputLine
+		fmt.Fprintf(&b, "Rewrite these notes as one paragraph for the %q section of the README. Reply with the paragraph on a single line.\n\n", section)
+	} else {
+		fmt.Fprintf(&b, "Rewrite these notes as a Markdown bullet list for the %q section of the README.\n\n", section)
+	}
+	for _, line := range FormatNotes(notes) {
+		b.WriteString(line + "\n")
+	}
+
+	messages := []ChatMessage{
+		{Role: "system", Content: polishSystemPrompt},
+		{Role: "user", Content: b.String()},
+	}
+	reply, err := Chat(cfg, messages)
+	if err != nil {
+		return nil, err
+	}
+	return ParseModeOutput(output, reply)
+}
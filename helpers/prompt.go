@@ -0,0 +1,71 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultUserPrompt is used when the config doesn't set llm.user_prompt.
+const DefaultUserPrompt = `Reword this note: "{{.Note}}"`
+
+// NeighbourBullets is how many of the section's bullets around the
+// insertion point a prompt template gets as {{.Bullets}}.
+const NeighbourBullets = 5
+
+// PromptData is everything a system or user prompt template can reference.
+type PromptData struct {
+	Note    string   // the note as typed by the user
+	Section []string // heading path the note is going under
+	Bullets []string // the section's bullets nearest to where the note goes
+	Project string   // project name (top-level heading of the notes file)
+	Date    string   // today's date, YYYY-MM-DD
+
+	content string // the whole file, for the few-shot examples
+}
+
+// NewPromptData fills in the date and project for a note going under placement.
+func NewPromptData(content string, placement []string, note string) PromptData {
+	project := ""
+	if len(placement) > 0 {
+		project = placement[0]
+	} else if tree := ParseHeadings(content); len(tree.Children) > 0 {
+		project = tree.Children[0].Title
+	}
+
+	return PromptData{
+		Note:    note,
+		Section: placement,
+		Bullets: NearestBullets(content, placement, NeighbourBullets),
+		Project: project,
+		Date:    time.Now().Format("2006-01-02"),
+		content: content,
+	}
+}
+
+// Path returns the heading path joined for display, e.g. "Design > API".
+func (d PromptData) Path() string {
+	return strings.Join(d.Section, " > ")
+}
+
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// RenderPrompt executes a prompt template against data.
+func RenderPrompt(tmpl string, data PromptData) (string, error) {
+	t, err := template.New("prompt").Funcs(promptFuncs).Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("could not parse prompt template: %w", err)
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("could not render prompt template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
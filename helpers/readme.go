@@ -0,0 +1,179 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"writeme/config"
+)
+
+// KeepMarker right under a README heading marks the section as hand-written:
+// `writeme readme generate` leaves it exactly as it is.
+const KeepMarker = "<!-- writeme:keep -->"
+
+const readmeSystemPrompt = `You write README.md files for software projects from the developer's notes.
+Rules:
+- Reply with the complete README in Markdown and nothing else. No code fence around it.
+- Start with a "# " heading with the project name, then these "## " sections in order: Overview, Installation, Usage, Configuration. Add other sections only if the notes call for them.
+- Use only facts from the notes and the current README. Do not invent commands, flags, URLs or features.
+- Keep what is still accurate in the current README; update what the notes contradict.
+- Be concise and concrete; prefer short paragraphs, lists and code blocks for commands.`
+
+// KeptSection is a hand-written README section, with its subsections.
+type KeptSection struct {
+	Title string
+	Lines []string // heading included
+	After string   // title of the heading before it, to put it back in place
+}
+
+// sectionEnd returns where the section starting at heading index k of
+// headings ends: the next heading of the same or a higher level.
+func sectionEnd(lines []string, headings []int, k int) int {
+	level, _, _ := parseHeading(lines[headings[k]])
+	for _, h := range headings[k+1:] {
+		if l, _, _ := parseHeading(lines[h]); l <= level {
+			return h
+		}
+	}
+	return len(lines)
+}
+
+// HandWrittenSections finds the sections of a README marked with KeepMarker
+// on the first non-blank line under their heading.
+func HandWrittenSections(content string) []KeptSection {
+	lines := strings.Split(content, "\n")
+	headings := HeadingLines(lines)
+
+	var kept []KeptSection
+	for k := 0; k < len(headings); k++ {
+		h := headings[k]
+		if !hasKeepMarker(lines, h) {
+			continue
+		}
+		_, title, _ := parseHeading(lines[h])
+		end := sectionEnd(lines, headings, k)
+
+		sec := KeptSection{Title: title, Lines: trimBlankTail(lines[h:end])}
+		if k > 0 {
+			_, sec.After, _ = parseHeading(lines[headings[k-1]])
+		}
+		kept = append(kept, sec)
+
+		// Skip the subsections, they're part of this one
+		for k+1 < len(headings) && headings[k+1] < end {
+			k++
+		}
+	}
+	return kept
+}
+
+func hasKeepMarker(lines []string, h int) bool {
+	for _, line := range lines[h+1:] {
+		if line = strings.TrimSpace(line); line != "" {
+			return line == KeepMarker
+		}
+	}
+	return false
+}
+
+func trimBlankTail(lines []string) []string {
+	end := len(lines)
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return append([]string{}, lines[:end]...)
+}
+
+// GenerateReadme asks the configured backend for a README built from notes,
+// using the current README as a starting point. Hand-written sections are
+// put back verbatim afterwards.
+func GenerateReadme(cfg *config.Config, notes, current string) (string, error) {
+	kept := HandWrittenSections(current)
+
+	var b strings.Builder
+	b.WriteString("Notes (NOTES.md):\n\n")
+	b.WriteString(notes)
+	b.WriteString("\n\n")
+	if strings.TrimSpace(current) != "" {
+		b.WriteString("Current README.md:\n\n")
+		b.WriteString(current)
+		b.WriteString("\n\n")
+	}
+	if len(kept) > 0 {
+		var titles []string
+		for _, k := range kept {
+			titles = append(titles, fmt.Sprintf("%q", k.Title))
+		}
+		fmt.Fprintf(&b, "These sections are written by hand and will be added back unchanged, so leave them out: %s.\n\n", strings.Join(titles, ", "))
+	}
+	b.WriteString("Write the new README.md.")
+
+	messages := []ChatMessage{
+		{Role: "system", Content: readmeSystemPrompt},
+		{Role: "user", Content: b.String()},
+	}
+	reply, err := Chat(cfg, messages)
+	if err != nil {
+		return "", err
+	}
+
+	draft := stripFence(strings.TrimSpace(reply))
+	if draft == "" {
+		return "", fmt.Errorf("model returned an empty README")
+	}
+	return MergeKeptSections(draft, kept) + "\n", nil
+}
+
+// stripFence unwraps a reply that came back inside a ```markdown block.
+func stripFence(text string) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) >= 2 && strings.HasPrefix(lines[0], "```") && strings.TrimSpace(lines[len(lines)-1]) == "```" {
+		return strings.TrimSpace(strings.Join(lines[1:len(lines)-1], "\n"))
+	}
+	return text
+}
+
+// MergeKeptSections puts hand-written sections into draft: in place of a
+// section with the same title, else after the section they used to follow,
+// else at the end.
+func MergeKeptSections(draft string, kept []KeptSection) string {
+	lines := strings.Split(draft, "\n")
+	for _, sec := range kept {
+		headings := HeadingLines(lines)
+
+		if k := findHeading(lines, headings, sec.Title); k >= 0 {
+			end := sectionEnd(lines, headings, k)
+			lines = splice(lines, headings[k], end, withBlankAfter(sec.Lines, end < len(lines)))
+			continue
+		}
+
+		at := len(lines)
+		if k := findHeading(lines, headings, sec.After); k >= 0 {
+			at = sectionEnd(lines, headings, k)
+		}
+		block := withBlankAfter(sec.Lines, at < len(lines))
+		if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+			block = append([]string{""}, block...)
+		}
+		lines = splice(lines, at, at, block)
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+func findHeading(lines []string, headings []int, title string) int {
+	if title == "" {
+		return -1
+	}
+	for k, h := range headings {
+		if _, t, _ := parseHeading(lines[h]); strings.EqualFold(t, title) {
+			return k
+		}
+	}
+	return -1
+}
+
+func withBlankAfter(lines []string, more bool) []string {
+	if !more {
+		return lines
+	}
+	return append(append([]string{}, lines...), "")
+}
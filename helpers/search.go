@@ -0,0 +1,177 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Search modes for SearchNotes.
+const (
+	SearchSubstring = "substring"
+	SearchRegex     = "regex"
+	SearchFuzzy     = "fuzzy"
+)
+
+// SearchHit is a heading or bullet that matched a search.
+type SearchHit struct {
+	File    string   `json:"file"`
+	Line    int      `json:"line"` // 1-based
+	Section []string `json:"section"`
+	Kind    string   `json:"kind"` // "heading" or "bullet"
+	Text    string   `json:"text"`
+	Date    string   `json:"date,omitempty"`
+	Score   int      `json:"score,omitempty"` // fuzzy mode only
+
+	Similarity float64 `json:"similarity,omitempty"` // semantic search only
+}
+
+// SearchOptions narrows a search.
+type SearchOptions struct {
+	Mode  string
+	In    []string  // only sections whose path contains these headings, in a row
+	Since time.Time // only notes dated on or after this (zero = any)
+	Until time.Time // only notes dated on or before this (zero = any)
+}
+
+var dateStamp = regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})\b`)
+
+// NoteDate finds a YYYY-MM-DD timestamp in a note.
+func NoteDate(text string) (time.Time, bool) {
+	for _, m := range dateStamp.FindAllString(text, -1) {
+		if t, err := time.Parse("2006-01-02", m); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// InSection reports whether path contains the headings of in, consecutively
+// and case-insensitively. "Design/API" matches "proj > Design > API > Auth".
+func InSection(path, in []string) bool {
+	if len(in) == 0 {
+		return true
+	}
+	for start := 0; start+len(in) <= len(path); start++ {
+		match := true
+		for i, want := range in {
+			if !strings.EqualFold(path[start+i], want) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitSectionFlag turns "Design/API" or "Design > API" into headings.
+func SplitSectionFlag(s string) []string {
+	var parts []string
+	for _, p := range strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '>' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// SearchNotes finds headings and bullets in content matching query.
+func SearchNotes(file, content, query string, opts SearchOptions) ([]SearchHit, error) {
+	match, err := searchMatcher(query, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+	dated := !opts.Since.IsZero() || !opts.Until.IsZero()
+
+	lines := strings.Split(content, "\n")
+	var hits []SearchHit
+	for _, h := range HeadingLines(lines) {
+		path := HeadingPathAt(lines, h)
+		if !InSection(path, opts.In) {
+			continue
+		}
+
+		_, title, _ := parseHeading(lines[h])
+		if score, ok := match(title); ok && !dated {
+			hits = append(hits, SearchHit{File: file, Line: h + 1, Section: path, Kind: "heading", Text: title, Score: score})
+		}
+
+		for _, item := range SectionItems(lines, h) {
+			score, ok := match(item.Text)
+			if !ok {
+				continue
+			}
+			hit := SearchHit{File: file, Line: item.Start + 1, Section: path, Kind: "bullet", Text: item.Text, Score: score}
+			if t, ok := NoteDate(item.Text); ok {
+				hit.Date = t.Format("2006-01-02")
+				if (!opts.Since.IsZero() && t.Before(opts.Since)) || (!opts.Until.IsZero() && t.After(opts.Until)) {
+					continue
+				}
+			} else if dated {
+				continue // no timestamp, can't tell
+			}
+			hits = append(hits, hit)
+		}
+	}
+
+	if opts.Mode == SearchFuzzy {
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	} else {
+		for i := range hits {
+			hits[i].Score = 0
+		}
+	}
+	return hits, nil
+}
+
+// AllNotes returns every bullet in content as a hit, limited to the sections
+// matching in.
+func AllNotes(file, content string, in []string) []SearchHit {
+	lines := strings.Split(content, "\n")
+	var notes []SearchHit
+	for _, h := range HeadingLines(lines) {
+		path := HeadingPathAt(lines, h)
+		if !InSection(path, in) {
+			continue
+		}
+		for _, item := range SectionItems(lines, h) {
+			hit := SearchHit{File: file, Line: item.Start + 1, Section: path, Kind: "bullet", Text: item.Text}
+			if t, ok := NoteDate(item.Text); ok {
+				hit.Date = t.Format("2006-01-02")
+			}
+			notes = append(notes, hit)
+		}
+	}
+	return notes
+}
+
+// searchMatcher returns a function that scores text against query.
+func searchMatcher(query, mode string) (func(string) (int, bool), error) {
+	switch mode {
+	case SearchRegex:
+		re, err := regexp.Compile("(?i)" + query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		return func(text string) (int, bool) { return 0, re.MatchString(text) }, nil
+	case SearchFuzzy:
+		// A stray letter here and there matches anything, so ask for a
+		// score of at least one point per query character
+		minScore := utf8.RuneCountInString(query)
+		return func(text string) (int, bool) {
+			score, _, ok := FuzzyMatch(query, text)
+			return score, ok && score >= minScore
+		}, nil
+	case SearchSubstring, "":
+		q := strings.ToLower(query)
+		return func(text string) (int, bool) { return 0, strings.Contains(strings.ToLower(text), q) }, nil
+	default:
+		return nil, fmt.Errorf("unknown search mode %q", mode)
+	}
+}
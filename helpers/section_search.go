@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// sectionItem is a row in the picker's "search all sections" list.
+type sectionItem struct {
+	path   []string
+	recent bool
+}
+
+func (i sectionItem) Title() string { return strings.Join(i.path, PathSeparator) }
+
+func (i sectionItem) Description() string {
+	if i.recent {
+		return "recently used"
+	}
+	return ""
+}
+
+func (i sectionItem) FilterValue() string { return strings.Join(i.path, PathSeparator) }
+
+// AllSectionPaths lists every heading path in the tree, in document order.
+func AllSectionPaths(root *HeadingNode) [][]string {
+	var paths [][]string
+	var walk func(node *HeadingNode, prefix []string)
+	walk = func(node *HeadingNode, prefix []string) {
+		for _, child := range node.Children {
+			path := append(append([]string{}, prefix...), child.Title)
+			paths = append(paths, path)
+			walk(child, path)
+		}
+	}
+	walk(root, nil)
+	return paths
+}
+
+// recencyBonus ranks recently used sections higher: the most recent one gets
+// the biggest boost.
+func recencyBonus(recent [][]string, path []string) int {
+	for i, r := range recent {
+		if SamePath(r, path) {
+			return max(0, 10-i) * 2
+		}
+	}
+	return 0
+}
+
+// newSectionSearchList builds the flattened list of every section, recent
+// ones first, ranked by fuzzy score plus recency when filtered.
+func newSectionSearchList(root *HeadingNode, recent [][]string) list.Model {
+	var items []list.Item
+	var paths [][]string
+	seen := map[string]bool{}
+
+	all := AllSectionPaths(root)
+	exists := map[string]bool{}
+	for _, p := range all {
+		exists[strings.Join(p, "\x00")] = true
+	}
+
+	add := func(path []string, isRecent bool) {
+		key := strings.Join(path, "\x00")
+		if seen[key] || !exists[key] {
+			return
+		}
+		seen[key] = true
+		items = append(items, sectionItem{path: path, recent: isRecent})
+		paths = append(paths, path)
+	}
+	for _, p := range recent {
+		add(p, true)
+	}
+	for _, p := range all {
+		add(p, false)
+	}
+
+	l := list.New(items, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Search all sections (use / between levels, e.g. api/auth)"
+	l.SetShowStatusBar(false)
+	l.DisableQuitKeybindings()
+	l.KeyMap.AcceptWhileFiltering.SetHelp("enter", "choose")
+	l.KeyMap.CancelWhileFiltering.SetHelp("esc/tab", "browse by level")
+	l.Filter = func(term string, targets []string) []list.Rank {
+		var ranks []list.Rank
+		scores := map[int]int{}
+		for i := range targets {
+			score, matched, ok := FuzzyMatchPath(term, paths[i])
+			if !ok {
+				continue
+			}
+			scores[i] = score + recencyBonus(recent, paths[i])
+			ranks = append(ranks, list.Rank{Index: i, MatchedIndexes: matched})
+		}
+		sort.SliceStable(ranks, func(a, b int) bool {
+			return scores[ranks[a].Index] > scores[ranks[b].Index]
+		})
+		return ranks
+	}
+	return l
+}
@@ -0,0 +1,139 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SelectNotesModel is a checklist of notes: pick any number of them with
+// space, confirm with enter.
+type SelectNotesModel struct {
+	title     string
+	notes     []SearchHit
+	selected  map[int]bool
+	cursor    int
+	offset    int // first visible row
+	height    int
+	confirmed bool
+}
+
+func NewSelectNotesModel(title string, notes []SearchHit) SelectNotesModel {
+	return SelectNotesModel{title: title, notes: notes, selected: map[int]bool{}, height: 20}
+}
+
+func (m SelectNotesModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m SelectNotesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = max(1, msg.Height-4) // title, blank, blank, help
+		m.scroll()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.notes)-1 {
+				m.cursor++
+			}
+		case "pgup":
+			m.cursor = max(0, m.cursor-m.height)
+		case "pgdown":
+			m.cursor = min(len(m.notes)-1, m.cursor+m.height)
+		case " ", "x":
+			m.selected[m.cursor] = !m.selected[m.cursor]
+		case "a":
+			all := len(m.Selected()) < len(m.notes)
+			for i := range m.notes {
+				m.selected[i] = all
+			}
+		case "enter":
+			if len(m.Selected()) == 0 {
+				m.selected[m.cursor] = true // enter on nothing picks the current row
+			}
+			m.confirmed = true
+			return m, tea.Quit
+		case "q", "esc", "ctrl+c":
+			m.confirmed = false
+			return m, tea.Quit
+		}
+		m.scroll()
+	}
+	return m, nil
+}
+
+// scroll keeps the cursor on screen.
+func (m *SelectNotesModel) scroll() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.height {
+		m.offset = m.cursor - m.height + 1
+	}
+}
+
+// Selected returns the picked notes in file order.
+func (m SelectNotesModel) Selected() []SearchHit {
+	var out []SearchHit
+	for i, n := range m.notes {
+		if m.selected[i] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (m SelectNotesModel) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(m.title) + helpStyle.Render(fmt.Sprintf("  %d of %d selected", len(m.Selected()), len(m.notes))) + "\n\n")
+
+	end := min(len(m.notes), m.offset+m.height)
+	for i := m.offset; i < end; i++ {
+		n := m.notes[i]
+		box := "[ ]"
+		if m.selected[i] {
+			box = addedStyle.Render("[x]")
+		}
+		text := strings.SplitN(n.Text, "\n", 2)[0]
+		row := fmt.Sprintf("%s %s  %s", box, gutterStyle.Render(strings.Join(n.Section, " > ")), text)
+		if i == m.cursor {
+			row = selectedStyle.Render("›") + " " + row
+		} else {
+			row = "  " + row
+		}
+		b.WriteString(row + "\n")
+	}
+
+	b.WriteString("\n" + helpStyle.Render("space select · a all/none · ↑/↓ move · enter confirm · q cancel"))
+	return b.String()
+}
+
+// RunSelectNotes shows the checklist and returns the picked notes, or nil if
+// the user cancelled.
+func RunSelectNotes(title string, notes []SearchHit) ([]SearchHit, error) {
+	tty, done, err := TerminalInput()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	p := tea.NewProgram(NewSelectNotesModel(title, notes), tea.WithInput(tty), tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	m := finalModel.(SelectNotesModel)
+	if !m.confirmed {
+		return nil, nil
+	}
+	return m.Selected(), nil
+}
@@ -0,0 +1,122 @@
+package helpers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"writeme/config"
+)
+
+// maxRecent is how many recently used sections we remember per file.
+const maxRecent = 20
+
+// RecentSection is a section a note was recently added to.
+type RecentSection struct {
+	Path []string  `json:"path"`
+	Used time.Time `json:"used"`
+}
+
+// ProjectState is what writeme remembers about one notes file between runs.
+// It lives in the state dir, keyed by the notes file's absolute path.
+type ProjectState struct {
+	File   string          `json:"file"`
+	Recent []RecentSection `json:"recent"`
+
+	path string // where the state is stored
+}
+
+// LoadProjectState reads the state for notesFile. A missing state file just
+// means a fresh state.
+func LoadProjectState(notesFile string) (*ProjectState, error) {
+	abs, err := filepath.Abs(notesFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s: %w", notesFile, err)
+	}
+
+	dir, err := config.ResolveStateDir()
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve state dir: %w", err)
+	}
+	sum := sha1.Sum([]byte(abs))
+	path := filepath.Join(dir, "projects", hex.EncodeToString(sum[:8])+".json")
+
+	state := &ProjectState{File: abs, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("could not parse state file %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// TouchSection moves section to the front of the recent list.
+func (s *ProjectState) TouchSection(section []string) {
+	if len(section) == 0 {
+		return
+	}
+	recent := []RecentSection{{Path: section, Used: time.Now()}}
+	for _, r := range s.Recent {
+		if !SamePath(r.Path, section) {
+			recent = append(recent, r)
+		}
+	}
+	if len(recent) > maxRecent {
+		recent = recent[:maxRecent]
+	}
+	s.Recent = recent
+}
+
+// PruneSections drops recent sections that aren't in existing any more
+// (the heading was renamed or removed). It reports whether anything changed.
+func (s *ProjectState) PruneSections(existing [][]string) bool {
+	var kept []RecentSection
+	for _, r := range s.Recent {
+		for _, path := range existing {
+			if SamePath(r.Path, path) {
+				kept = append(kept, r)
+				break
+			}
+		}
+	}
+	changed := len(kept) != len(s.Recent)
+	s.Recent = kept
+	return changed
+}
+
+// LastSection returns the most recently used section, if any.
+func (s *ProjectState) LastSection() ([]string, bool) {
+	if len(s.Recent) == 0 {
+		return nil, false
+	}
+	return s.Recent[0].Path, true
+}
+
+// RecentPaths returns the recent sections, most recent first.
+func (s *ProjectState) RecentPaths() [][]string {
+	var paths [][]string
+	for _, r := range s.Recent {
+		paths = append(paths, r.Path)
+	}
+	return paths
+}
+
+// Save writes the state back to the state dir.
+func (s *ProjectState) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("could not create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode state: %w", err)
+	}
+	return WriteFileAtomic(s.path, data, 0644)
+}
@@ -0,0 +1,161 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"writeme/config"
+)
+
+// SummaryHeading is the subsection `writeme summarize --write` keeps up to
+// date. Notes under it are never summarized again.
+const SummaryHeading = "Summary"
+
+const summarizeSystemPrompt = `You summarize a developer's project notes.
+Rules:
+- Reply with a short Markdown bullet list: the key points, decisions and open questions.
+- Use only what the notes say. Do not add new information.
+- Merge notes that say the same thing.
+- No preamble, no closing remarks.`
+
+// maxReduceRounds caps how often partial summaries get summarized again.
+const maxReduceRounds = 3
+
+// NotesUnder returns the bullets in the section on line h and all its
+// subsections, leaving out Summary subsections.
+func NotesUnder(file string, lines []string, h int) []SearchHit {
+	section := HeadingPathAt(lines, h)
+
+	var notes []SearchHit
+	for _, n := range AllNotes(file, strings.Join(lines, "\n"), nil) {
+		if n.Line-1 > h && hasPathPrefix(n.Section, section) && !InSummary(n.Section[len(section):]) {
+			notes = append(notes, n)
+		}
+	}
+	return notes
+}
+
+// InSummary reports whether a heading path runs through a Summary
+// subsection.
+func InSummary(subpath []string) bool {
+	for _, title := range subpath {
+		if strings.EqualFold(title, SummaryHeading) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	return len(path) >= len(prefix) && SamePath(path[:len(prefix)], prefix)
+}
+
+// SummarizeNotes asks the configured backend for a summary of notes. Notes
+// that don't fit in chunkTokens are summarized in chunks first and the
+// partial summaries merged afterwards.
+func SummarizeNotes(cfg *config.Config, section []string, notes []string, chunkTokens int) ([]string, error) {
+	path := strings.Join(section, PathSeparator)
+	for round := 0; ; round++ {
+		chunks := chunkNotes(notes, chunkTokens)
+		if len(chunks) <= 1 || round == maxReduceRounds {
+			return summarizeChunk(cfg, path, notes, round > 0)
+		}
+
+		var partial []string
+		for i, chunk := range chunks {
+			out, err := summarizeChunk(cfg, path, chunk, round > 0)
+			if err != nil {
+				return nil, fmt.Errorf("could not summarize part %d of %d: %w", i+1, len(chunks), err)
+			}
+			partial = append(partial, out...)
+		}
+		notes = partial
+	}
+}
+
+// chunkNotes splits notes into runs of at most maxTokens (estimated). A
+// single note bigger than that gets a chunk of its own.
+func chunkNotes(notes []string, maxTokens int) [][]string {
+	if maxTokens <= 0 {
+		return [][]string{notes}
+	}
+
+	var chunks [][]string
+	var current []string
+	used := 0
+	for _, n := range notes {
+		cost := EstimateTokens(n)
+		if len(current) > 0 && used+cost > maxTokens {
+			chunks = append(chunks, current)
+			current, used = nil, 0
+		}
+		current = append(current, n)
+		used += cost
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+func summarizeChunk(cfg *config.Config, path string, notes []string, merging bool) ([]string, error) {
+	var b strings.Builder
+	if merging {
+		fmt.Fprintf(&b, "These are summaries of parts of the notes in the section %q. Merge them into one summary.\n\n", path)
+	} else {
+		fmt.Fprintf(&b, "Summarize these notes from the section %q.\n\n", path)
+	}
+	for _, line := range FormatNotes(notes) {
+		b.WriteString(line + "\n")
+	}
+
+	messages := []ChatMessage{
+		{Role: "system", Content: summarizeSystemPrompt},
+		{Role: "user", Content: b.String()},
+	}
+	reply, err := Chat(cfg, messages)
+	if err != nil {
+		return nil, err
+	}
+	return ParseModeOutput(OutputBullets, reply)
+}
+
+// WriteSummary puts bullets in the Summary subsection of the section on line
+// h, replacing what was there. A new Summary subsection goes before the
+// section's other subsections.
+func WriteSummary(lines []string, h int, bullets []string, now time.Time) []string {
+	level, _, _ := parseHeading(lines[h])
+	body := append([]string{"", fmt.Sprintf("_Summarized by writeme on %s._", now.Format("2006-01-02")), ""}, FormatNotes(bullets)...)
+	body = append(body, "")
+
+	// Where the section's own content ends, and its Summary if it has one
+	at, summary := len(lines), -1
+	headings := HeadingLines(lines)
+	for _, j := range headings {
+		if j <= h {
+			continue
+		}
+		l, title, _ := parseHeading(lines[j])
+		if at == len(lines) {
+			at = j
+		}
+		if l <= level {
+			break
+		}
+		if l == level+1 && strings.EqualFold(title, SummaryHeading) {
+			summary = j
+			break
+		}
+	}
+
+	if summary >= 0 {
+		return splice(lines, summary+1, sectionEndAt(lines, summary), body)
+	}
+
+	heading := strings.Repeat("#", level+1) + " " + SummaryHeading
+	block := append([]string{heading}, body...)
+	if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+		block = append([]string{""}, block...)
+	}
+	return splice(lines, at, at, block)
+}
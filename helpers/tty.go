@@ -0,0 +1,34 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// TerminalInput returns a file to read key presses from. Normally that's
+// just stdin, but when stdin is a pipe (`... | writeme note -`) the prompts
+// have to read from the terminal directly. Call done when finished with it.
+func TerminalInput() (f *os.File, done func(), err error) {
+	if stdinIsTerminal() {
+		return os.Stdin, func() {}, nil
+	}
+
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+	f, err = os.Open(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stdin is not a terminal and could not open %s: %w", name, err)
+	}
+	return f, func() { f.Close() }, nil
+}
+
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}